@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ayazumi/biliCLI/internal/config"
+	"github.com/ayazumi/biliCLI/internal/library"
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// ========== analyze 子命令 ==========
+// mytui analyze [-j N] [-f]：测量曲库中每首的响度，结果写入用户数据目录供音量均衡使用
+
+// 每测完这么多首写一次盘，中途退出也不会全部白做
+const analyzeSaveEvery = 10
+
+type analyzeJob struct {
+	cid  uint64
+	name string
+}
+
+type analyzeResult struct {
+	analyzeJob
+	loudness userdata.Loudness
+	err      error
+}
+
+func runAnalyze(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	workers := fs.Int("j", cfg.Loudness.Workers, "同时运行的 ffmpeg 数（0 为 CPU 核数的一半）")
+	force := fs.Bool("f", false, "重新测量已有结果的曲目")
+	fs.Parse(args)
+	if *workers <= 0 {
+		*workers = max(runtime.NumCPU()/2, 1)
+	}
+
+	if _, err := os.Stat(TreeJSONPath); err != nil {
+		return fmt.Errorf("未找到 %s，请先在 TUI 中按 B 构建", TreeJSONPath)
+	}
+	all, err := userdata.LoadLoudness()
+	if err != nil {
+		return err
+	}
+
+	var jobs []analyzeJob
+	for _, g := range loadTree() {
+		for _, t := range g.Titles {
+			for _, item := range t.Items {
+				if _, done := all[item.CID]; done && !*force {
+					continue
+				}
+				name := t.Name
+				if item.Title != t.Name {
+					name += ":" + item.Title
+				}
+				jobs = append(jobs, analyzeJob{cid: item.CID, name: name})
+			}
+		}
+	}
+	if len(jobs) == 0 {
+		fmt.Println("所有曲目都已测量过（-f 重新测量）")
+		return nil
+	}
+	fmt.Printf("测量 %d 首，%d 个并发\n", len(jobs), *workers)
+
+	// Ctrl+C 后不再派发新任务，已测完的结果照常保存
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	queue := make(chan analyzeJob)
+	results := make(chan analyzeResult)
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				results <- measure(cfg.Root, job)
+			}
+		}()
+	}
+	go func() {
+		defer close(queue)
+		for _, job := range jobs {
+			select {
+			case queue <- job:
+			case <-stop:
+				fmt.Println("已中断，等待进行中的测量结束…")
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	done, failed := 0, 0
+	for r := range results {
+		done++
+		if r.err != nil {
+			failed++
+			fmt.Printf("[%d/%d] ❗ %s: %v\n", done, len(jobs), r.name, r.err)
+			continue
+		}
+		all[r.cid] = r.loudness
+		fmt.Printf("[%d/%d] %s: %.1f LUFS, %.1f dBTP\n", done, len(jobs), r.name, r.loudness.Integrated, r.loudness.Peak)
+		if (done-failed)%analyzeSaveEvery == 0 {
+			if err := userdata.SaveLoudness(all); err != nil {
+				return err
+			}
+		}
+	}
+	if err := userdata.SaveLoudness(all); err != nil {
+		return err
+	}
+	fmt.Printf("完成：成功 %d 首，失败 %d 首\n", done-failed, failed)
+	return nil
+}
+
+func measure(root string, job analyzeJob) analyzeResult {
+	r := analyzeResult{analyzeJob: job}
+	file, err := library.FindAudio(root, job.cid)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	integrated, peak, err := library.MeasureLoudness(file)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	r.loudness = userdata.Loudness{Integrated: integrated, Peak: peak, Analyzed: time.Now().Unix()}
+	return r
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ayazumi/biliCLI/internal/player"
+)
+
+// ========== 通用列表选择 ==========
+
+// chooseKind 区分 StateChoose 的用途
+type chooseKind int
+
+const (
+	ChooseDevice chooseKind = iota
+	ChooseEQ
+	ChoosePlaylist       // 加入哪个播放列表
+	ChooseDeletePlaylist // 确认删除播放列表
+)
+
+type choice struct {
+	label string
+	value string
+}
+
+type chooser struct {
+	kind    chooseKind
+	title   string
+	choices []choice
+	cursor  int
+}
+
+func (m *model) openChooser(kind chooseKind, title string, choices []choice, current string) {
+	c := chooser{kind: kind, title: title, choices: choices}
+	for i, ch := range choices {
+		if ch.value == current {
+			c.cursor = i
+			break
+		}
+	}
+	m.chooser = c
+	m.state = StateChoose
+}
+
+func (m *model) chooserKey(key string) tea.Cmd {
+	c := &m.chooser
+	switch key {
+	case "j", "down":
+		if c.cursor < len(c.choices)-1 {
+			c.cursor++
+		}
+	case "k", "up":
+		if c.cursor > 0 {
+			c.cursor--
+		}
+	case "enter":
+		m.state = StateTUI
+		if c.cursor < len(c.choices) {
+			return m.submitChoice(c.choices[c.cursor].value)
+		}
+	case "esc", "q":
+		m.state = StateTUI
+		m.pendingCIDs = nil
+	}
+	return nil
+}
+
+func (m *model) submitChoice(value string) tea.Cmd {
+	switch m.chooser.kind {
+	case ChooseDevice:
+		m.setDevice(value)
+	case ChooseEQ:
+		m.setEQ(value)
+	case ChoosePlaylist:
+		return m.choosePlaylist(value)
+	case ChooseDeletePlaylist:
+		m.deletePlaylist(value)
+	}
+	return nil
+}
+
+func (m model) chooserView() string {
+	var b strings.Builder
+	b.WriteString("\n" + m.chooser.title + "\n\n")
+	for i, ch := range m.chooser.choices {
+		cursor := "  "
+		if i == m.chooser.cursor {
+			cursor = "> "
+		}
+		b.WriteString(cursor + ch.label + "\n")
+	}
+	b.WriteString("\n（j/k 选择，Enter 确认，Esc 取消）")
+	return b.String()
+}
+
+// ========== 输出设备 ==========
+func (m *model) openDeviceChooser() {
+	current := m.pb.player.Device()
+	if current == "" {
+		current = "default"
+	}
+	var choices []choice
+	for _, d := range player.ListDevices() {
+		choices = append(choices, choice{label: fmt.Sprintf("%-28s %s", d.ID, d.Description), value: d.ID})
+	}
+	m.openChooser(ChooseDevice, "选择输出设备（下一首起生效）", choices, current)
+}
+
+func (m *model) setDevice(id string) {
+	m.pb.player.SetDevice(id)
+	if _, ok := m.pb.player.Track(); ok {
+		m.pb.status = "输出设备: " + id + "（下一首起生效）"
+	} else {
+		m.pb.status = "输出设备: " + id
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ayazumi/biliCLI/internal/config"
+)
+
+// ========== 均衡器 ==========
+// 预设展开成 ffmpeg 的 equalizer 滤镜链，接在音量均衡之后
+
+// 内置预设，config.json 的 eq.presets 中同名的预设会覆盖它们
+var builtinEQ = map[string][]config.EQBand{
+	"flat": nil,
+	"bass": {
+		{Freq: 60, Gain: 6},
+		{Freq: 150, Gain: 3},
+		{Freq: 400, Gain: -1},
+	},
+	"vocal": {
+		{Freq: 150, Gain: -2},
+		{Freq: 1000, Gain: 2},
+		{Freq: 3000, Gain: 4},
+		{Freq: 8000, Gain: 1},
+	},
+}
+
+var eqLabels = map[string]string{
+	"flat":  "原声",
+	"bass":  "低音增强",
+	"vocal": "人声",
+}
+
+// eqPresets 返回全部预设名：内置的在前，自定义的按名称排序
+func (m *model) eqPresets() []string {
+	names := []string{"flat", "bass", "vocal"}
+	var custom []string
+	for name := range m.cfg.EQ.Presets {
+		if _, ok := builtinEQ[name]; !ok {
+			custom = append(custom, name)
+		}
+	}
+	sort.Strings(custom)
+	return append(names, custom...)
+}
+
+func (m *model) eqBands(name string) ([]config.EQBand, bool) {
+	if bands, ok := m.cfg.EQ.Presets[name]; ok {
+		return bands, true
+	}
+	bands, ok := builtinEQ[name]
+	return bands, ok
+}
+
+// eqFilter 返回当前预设的滤镜链，原声时为空
+func (m *model) eqFilter() string {
+	bands, _ := m.eqBands(m.eqPreset)
+	var filters []string
+	for _, b := range bands {
+		width := b.Width
+		if width <= 0 {
+			width = 1
+		}
+		filters = append(filters, fmt.Sprintf("equalizer=f=%g:t=q:w=%g:g=%g", b.Freq, width, b.Gain))
+	}
+	return strings.Join(filters, ",")
+}
+
+func eqLabel(name string) string {
+	if label, ok := eqLabels[name]; ok {
+		return label
+	}
+	return name
+}
+
+func (m *model) openEQChooser() {
+	var choices []choice
+	for _, name := range m.eqPresets() {
+		label := eqLabel(name)
+		if label != name {
+			label = fmt.Sprintf("%-8s %s", name, label)
+		}
+		choices = append(choices, choice{label: label, value: name})
+	}
+	m.openChooser(ChooseEQ, "选择均衡器预设", choices, m.eqPreset)
+}
+
+// setEQ 切换预设，正在播放的曲目从当前位置起立即生效
+func (m *model) setEQ(name string) {
+	m.eqPreset = name
+	m.applyTrackFilters()
+	m.pb.status = "均衡器: " + eqLabel(name)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ayazumi/biliCLI/internal/player"
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// ========== 收听记录 ==========
+// 每次开始、听完、跳过都追加到 history.jsonl；H 打开记录面板，按天分组，最新的在前
+
+// 播放到距结尾不足这个时长时算作听完，与播放进度的判断一致
+const finishMargin = 5 * time.Second
+
+// recordHistory 追加一条收听记录，pos 为曲目内的位置
+func (m *model) recordHistory(event string, t player.Track, pos time.Duration) {
+	e := userdata.HistoryEvent{
+		Time:      time.Now().Unix(),
+		CID:       t.CID,
+		Event:     event,
+		Pos:       pos.Seconds(),
+		Completed: event == userdata.HistoryFinish,
+	}
+	if err := userdata.AppendHistory(e); err != nil {
+		m.pb.status = "❗ " + err.Error()
+	}
+	m.updateStats(event, t)
+}
+
+// recordStop 记录被中途结束的曲目：已到结尾附近的算听完，否则算跳过
+func (m *model) recordStop(t player.Track, pos time.Duration) {
+	end := t.Duration
+	if t.End > 0 {
+		end = t.End
+	}
+	if end > 0 && pos >= end-finishMargin {
+		m.recordHistory(userdata.HistoryFinish, t, pos)
+		return
+	}
+	m.recordHistory(userdata.HistorySkip, t, pos)
+}
+
+// historyPlay 是一次收听：开始记录与随后同一 CID 的结束记录合并而成
+type historyPlay struct {
+	at        time.Time
+	cid       uint64
+	pos       float64 // 结束时播放到的位置
+	completed bool
+	ended     bool // 没有结束记录的是正在播放的曲目（或异常退出时的曲目）
+}
+
+// historyRow 是面板中的一行：日期标题（play 为 -1）或一次收听
+type historyRow struct {
+	header string
+	play   int
+}
+
+// collectPlays 把收听记录合并成一次次收听，最新的在前
+func collectPlays(events []userdata.HistoryEvent) []historyPlay {
+	var plays []historyPlay
+	open := make(map[uint64]int)
+	for _, e := range events {
+		if e.Event == userdata.HistoryStart {
+			open[e.CID] = len(plays)
+			plays = append(plays, historyPlay{at: time.Unix(e.Time, 0), cid: e.CID, pos: e.Pos})
+			continue
+		}
+		i, ok := open[e.CID]
+		if !ok {
+			i = len(plays)
+			plays = append(plays, historyPlay{at: time.Unix(e.Time, 0), cid: e.CID})
+		}
+		delete(open, e.CID)
+		plays[i].pos = e.Pos
+		plays[i].completed = e.Completed
+		plays[i].ended = true
+	}
+	for i, j := 0, len(plays)-1; i < j; i, j = i+1, j-1 {
+		plays[i], plays[j] = plays[j], plays[i]
+	}
+	return plays
+}
+
+var weekdays = []string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}
+
+// historyRows 在每天的第一次收听前插入日期标题
+func historyRows(plays []historyPlay) []historyRow {
+	var rows []historyRow
+	day := ""
+	for i, p := range plays {
+		if d := p.at.Format("2006-01-02"); d != day {
+			day = d
+			rows = append(rows, historyRow{header: d + " " + weekdays[p.at.Weekday()], play: -1})
+		}
+		rows = append(rows, historyRow{play: i})
+	}
+	return rows
+}
+
+func (m *model) openHistory() {
+	events, err := userdata.LoadHistory()
+	if err != nil {
+		m.pb.status = "❗ " + err.Error()
+	}
+	m.history = collectPlays(events)
+	m.historyRows = historyRows(m.history)
+	m.historyCursor = 0
+	m.historyTop = 0
+	if len(m.historyRows) > 1 {
+		m.historyCursor = 1 // 第一行是日期标题
+	}
+	m.state = StateHistory
+}
+
+// moveHistoryCursor 向 dir 方向移到下一个收听行，跳过日期标题
+func (m *model) moveHistoryCursor(dir int) {
+	for i := m.historyCursor + dir; i >= 0 && i < len(m.historyRows); i += dir {
+		if m.historyRows[i].play >= 0 {
+			m.historyCursor = i
+			break
+		}
+	}
+	m.scrollHistory()
+}
+
+func (m *model) scrollHistory() {
+	height := max(m.viewport.Height-1, 1)
+	if m.historyCursor < m.historyTop {
+		m.historyTop = m.historyCursor
+		// 连同日期标题一起显示
+		if m.historyTop > 0 && m.historyRows[m.historyTop-1].play < 0 {
+			m.historyTop--
+		}
+	} else if m.historyCursor >= m.historyTop+height {
+		m.historyTop = m.historyCursor - height + 1
+	}
+	m.historyTop = max(m.historyTop, 0)
+}
+
+func (m *model) historyKey(key string) tea.Cmd {
+	switch key {
+	case "H", "esc":
+		m.state = StateTUI
+		m.refreshViewport()
+		return nil
+	case "q", "ctrl+c":
+		return m.quit()
+	case "p":
+		return m.togglePause()
+	case "j", "down":
+		m.moveHistoryCursor(1)
+		return nil
+	case "k", "up":
+		m.moveHistoryCursor(-1)
+		return nil
+	}
+	if m.historyCursor >= len(m.historyRows) || m.historyRows[m.historyCursor].play < 0 {
+		return nil
+	}
+	cid := m.history[m.historyRows[m.historyCursor].play].cid
+	switch key {
+	case "enter":
+		return m.playSequence([]uint64{cid})
+	case "i":
+		m.enqueue([]uint64{cid}, true)
+	case "a":
+		m.enqueue([]uint64{cid}, false)
+	}
+	return nil
+}
+
+func (m model) historyView() string {
+	height := max(m.viewport.Height, 2)
+	var lines []string
+	lines = append(lines, fmt.Sprintf("收听记录（%d 次）", len(m.history)))
+	for i := m.historyTop; i < len(m.historyRows) && len(lines) < height; i++ {
+		row := m.historyRows[i]
+		if row.play < 0 {
+			lines = append(lines, "── "+row.header+" ──")
+			continue
+		}
+		p := m.history[row.play]
+		cursor := "  "
+		if i == m.historyCursor {
+			cursor = "> "
+		}
+		mark := "⏭"
+		switch {
+		case !p.ended:
+			mark = "▶"
+		case p.completed:
+			mark = "✔"
+		}
+		line := fmt.Sprintf("%s%s %s %s", cursor, p.at.Format("15:04"), mark, m.trackName(p.cid))
+		if p.ended {
+			line += "  " + formatSeconds(p.pos)
+			if item, ok := m.itemByCID(p.cid); ok && item.Duration > 0 {
+				line += "/" + formatSeconds(float64(item.Duration))
+			}
+		}
+		lines = append(lines, line)
+	}
+	if len(m.history) == 0 {
+		lines = append(lines, "  （空）")
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m model) historyHelpView() string {
+	return "\n\nj/k=上下  Enter=重新播放  i=下一首播放  a=加入队列末尾  p=暂停  H/Esc=返回目录  （✔ 听完  ⏭ 跳过  ▶ 正在播放）"
+}
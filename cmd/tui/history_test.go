@@ -0,0 +1,48 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+func TestCollectPlays(t *testing.T) {
+	at := func(day, hour, min int) time.Time { return time.Date(2024, 1, day, hour, min, 0, 0, time.Local) }
+	ev := func(tm time.Time, cid uint64, event string, pos float64, completed bool) userdata.HistoryEvent {
+		return userdata.HistoryEvent{Time: tm.Unix(), CID: cid, Event: event, Pos: pos, Completed: completed}
+	}
+	events := []userdata.HistoryEvent{
+		ev(at(1, 10, 0), 1, userdata.HistoryStart, 0, false),
+		ev(at(1, 10, 5), 1, userdata.HistoryFinish, 300, true),
+		ev(at(1, 23, 59), 2, userdata.HistoryStart, 30, false),
+		ev(at(2, 0, 1), 2, userdata.HistorySkip, 90, false),
+		ev(at(2, 9, 0), 3, userdata.HistorySkip, 50, false),  // 开始记录丢失
+		ev(at(2, 10, 0), 4, userdata.HistoryStart, 0, false), // 正在播放
+	}
+	want := []historyPlay{
+		{at: at(2, 10, 0), cid: 4},
+		{at: at(2, 9, 0), cid: 3, pos: 50, ended: true},
+		{at: at(1, 23, 59), cid: 2, pos: 90, ended: true}, // 按开始的时间归到前一天
+		{at: at(1, 10, 0), cid: 1, pos: 300, completed: true, ended: true},
+	}
+	plays := collectPlays(events)
+	if !slices.EqualFunc(plays, want, func(a, b historyPlay) bool {
+		return a.at.Equal(b.at) && a.cid == b.cid && a.pos == b.pos && a.completed == b.completed && a.ended == b.ended
+	}) {
+		t.Fatalf("collectPlays = %+v，期望 %+v", plays, want)
+	}
+
+	wantRows := []historyRow{
+		{header: "2024-01-02 周二", play: -1},
+		{play: 0},
+		{play: 1},
+		{header: "2024-01-01 周一", play: -1},
+		{play: 2},
+		{play: 3},
+	}
+	if rows := historyRows(plays); !slices.Equal(rows, wantRows) {
+		t.Errorf("historyRows = %v，期望 %v", rows, wantRows)
+	}
+}
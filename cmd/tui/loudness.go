@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// ========== 音量均衡 ==========
+type GainMode int
+
+const (
+	GainOff   GainMode = iota
+	GainTrack          // 每首单独调整到目标响度
+	GainAlbum          // 同一标题下的分P用同一增益，保留分P之间的响度差
+	gainModeCount
+)
+
+// 增益后真峰值不超过这个值，避免削波
+const gainPeakCeiling = -1.0
+
+func parseGainMode(s string) GainMode {
+	switch s {
+	case "track":
+		return GainTrack
+	case "album":
+		return GainAlbum
+	default:
+		return GainOff
+	}
+}
+
+func (g GainMode) String() string {
+	switch g {
+	case GainTrack:
+		return "单曲"
+	case GainAlbum:
+		return "专辑"
+	default:
+		return "关闭"
+	}
+}
+
+// albumLoudness 按能量平均合并同一标题下已测量的分P
+func albumLoudness(ls []userdata.Loudness) userdata.Loudness {
+	var energy float64
+	album := userdata.Loudness{Peak: math.Inf(-1)}
+	for _, l := range ls {
+		energy += math.Pow(10, l.Integrated/10)
+		album.Peak = math.Max(album.Peak, l.Peak)
+	}
+	album.Integrated = 10 * math.Log10(energy/float64(len(ls)))
+	return album
+}
+
+// gainDB 返回 cid 应施加的增益；没有测量结果时 ok 为 false
+func (m *model) gainDB(cid uint64) (float64, bool) {
+	// 分段使用整个视频的测量结果
+	l, ok := m.loudness[sourceCID(cid)]
+	if m.gainMode == GainOff || !ok {
+		return 0, false
+	}
+	if m.gainMode == GainAlbum {
+		if key, ok := m.titleOf[cid]; ok {
+			var ls []userdata.Loudness
+			for _, item := range m.groups[key[0]].Titles[key[1]].Items {
+				if l, ok := m.loudness[sourceCID(item.CID)]; ok {
+					ls = append(ls, l)
+				}
+			}
+			l = albumLoudness(ls)
+		}
+	}
+	gain := m.cfg.Loudness.Target - l.Integrated
+	if l.Peak+gain > gainPeakCeiling {
+		gain = gainPeakCeiling - l.Peak
+	}
+	return gain, true
+}
+
+// trackFilters 返回 cid 整首使用的滤镜链：音量均衡 → 均衡器
+func (m *model) trackFilters(cid uint64) string {
+	var filters []string
+	if gain, ok := m.gainDB(cid); ok {
+		filters = append(filters, fmt.Sprintf("volume=%.2fdB", gain))
+	}
+	filters = append(filters, m.eqFilter())
+	return joinFilters(filters...)
+}
+
+func joinFilters(filters ...string) string {
+	var parts []string
+	for _, f := range filters {
+		if f != "" {
+			parts = append(parts, f)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// applyTrackFilters 让当前曲目改用新的滤镜链；淡出中的曲目保持不变
+func (m *model) applyTrackFilters() {
+	t, ok := m.pb.player.Track()
+	if !ok || m.pb.fading {
+		return
+	}
+	if err := m.pb.player.SetFilters(m.trackFilters(t.CID)); err != nil {
+		m.pb.status = "❗ " + err.Error()
+	}
+}
+
+func (m *model) cycleGainMode() {
+	m.gainMode = (m.gainMode + 1) % gainModeCount
+	m.applyTrackFilters()
+	m.pb.status = "音量均衡: " + m.gainMode.String()
+	if m.gainMode != GainOff && len(m.loudness) == 0 {
+		m.pb.status += "（尚未测量响度，请先运行 mytui analyze）"
+	}
+}
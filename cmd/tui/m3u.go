@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ayazumi/biliCLI/internal/config"
+	"github.com/ayazumi/biliCLI/internal/library"
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// ========== M3U8 导入 / 导出 ==========
+// 导出的条目指向音频 m4s（或用 -copy 另存的音频文件），分段额外写 VLC 的起止选项；
+// 导入时先按路径中的 CID 目录匹配，再按 #EXTINF 标题和文件名匹配
+
+// 按起点匹配分段时允许的误差（秒），导出时起点只保留三位小数
+const m3uStartTolerance = 0.0005
+
+type m3uEntry struct {
+	Title string
+	Path  string
+	Start float64 // #EXTVLCOPT:start-time
+}
+
+// writeM3U 把条目写成 M3U8，find 返回 CID 对应的音频文件；
+// copyDir 不为空时先把音频另存到该目录，条目指向另存的文件
+func writeM3U(w io.Writer, items []Item, copyDir string, find func(cid uint64) (string, error)) (skipped []string, err error) {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#EXTM3U")
+	for i, item := range items {
+		file, err := find(sourceCID(item.CID))
+		if err != nil {
+			skipped = append(skipped, item.Title)
+			continue
+		}
+		path := file
+		segment := isSegment(item.CID) && copyDir == ""
+		if copyDir != "" {
+			path = filepath.Join(copyDir, fmt.Sprintf("%03d %s.m4a", i+1, safeFileName(item.Title)))
+			if err := library.ExportAudio(file, path, item.Start, item.End); err != nil {
+				return skipped, err
+			}
+		}
+		fmt.Fprintf(bw, "#EXTINF:%d,%s\n", item.Duration, item.Title)
+		if segment {
+			fmt.Fprintf(bw, "#EXTVLCOPT:start-time=%.3f\n", item.Start)
+			if item.End > 0 {
+				fmt.Fprintf(bw, "#EXTVLCOPT:stop-time=%.3f\n", item.End)
+			}
+		}
+		fmt.Fprintln(bw, path)
+	}
+	return skipped, bw.Flush()
+}
+
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', 0, ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+func parseM3U(r io.Reader) ([]m3uEntry, error) {
+	var entries []m3uEntry
+	var cur m3uEntry
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(sc.Text(), "\ufeff"))
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			if _, title, ok := strings.Cut(line, ","); ok {
+				cur.Title = strings.TrimSpace(title)
+			}
+		case strings.HasPrefix(line, "#EXTVLCOPT:start-time="):
+			cur.Start, _ = strconv.ParseFloat(strings.TrimPrefix(line, "#EXTVLCOPT:start-time="), 64)
+		case strings.HasPrefix(line, "#"):
+		default:
+			cur.Path = line
+			entries = append(entries, cur)
+			cur = m3uEntry{}
+		}
+	}
+	return entries, sc.Err()
+}
+
+// matchM3U 把条目对应回曲库中的 CID（或分段 ID），返回未能匹配的条目
+func matchM3U(entries []m3uEntry, lib map[uint64]Item) (cids []uint64, unmatched []string) {
+	byName := make(map[string]uint64, len(lib))
+	for cid, item := range lib {
+		byName[strings.ToLower(item.Title)] = cid
+	}
+	for _, e := range entries {
+		if cid, ok := matchM3UEntry(e, lib, byName); ok {
+			cids = append(cids, cid)
+			continue
+		}
+		name := e.Title
+		if name == "" {
+			name = e.Path
+		}
+		unmatched = append(unmatched, name)
+	}
+	return cids, unmatched
+}
+
+func matchM3UEntry(e m3uEntry, lib map[uint64]Item, byName map[string]uint64) (uint64, bool) {
+	// 缓存目录结构为 <root>/<CID>/*.m4s
+	if cid, err := strconv.ParseUint(filepath.Base(filepath.Dir(e.Path)), 10, 64); err == nil {
+		if _, ok := lib[cid]; ok {
+			return cid, true
+		}
+		// 已拆成分段的视频按起点找对应的分段
+		for id, item := range lib {
+			if isSegment(id) && sourceCID(id) == cid && math.Abs(item.Start-e.Start) <= m3uStartTolerance {
+				return id, true
+			}
+		}
+	}
+	if cid, ok := byName[strings.ToLower(e.Title)]; ok && e.Title != "" {
+		return cid, true
+	}
+	base := strings.TrimSuffix(filepath.Base(e.Path), filepath.Ext(e.Path))
+	if cid, ok := byName[strings.ToLower(base)]; ok {
+		return cid, true
+	}
+	// 由 -copy 导出的文件名带有 "001 " 序号前缀
+	if _, rest, ok := strings.Cut(base, " "); ok {
+		if cid, ok := byName[strings.ToLower(rest)]; ok {
+			return cid, true
+		}
+	}
+	return 0, false
+}
+
+// loadLibrary 供子命令使用：把曲库（含分段）和播放列表读到不带播放器的 model 中
+func loadLibrary() (model, error) {
+	var m model
+	if _, err := os.Stat(TreeJSONPath); err != nil {
+		return m, fmt.Errorf("未找到 %s，请先在 TUI 中按 B 构建", TreeJSONPath)
+	}
+	m.groups = loadTree()
+	segments, err := userdata.LoadSegments()
+	if err != nil {
+		return m, err
+	}
+	expandSegments(m.groups, segments)
+	m.indexTitles()
+	m.playlists, err = userdata.LoadPlaylists()
+	return m, err
+}
+
+// loadQueryData 读取智能播放列表的查询要用到的统计、评分和标签
+func (m *model) loadQueryData() error {
+	var err error
+	if m.stats, err = userdata.LoadStats(); err != nil {
+		return err
+	}
+	if m.ratings, err = userdata.LoadRatings(); err != nil {
+		return err
+	}
+	migrateTitleKeys(m.ratings.Titles, m.groups)
+	if m.tags, err = userdata.LoadTags(); err != nil {
+		return err
+	}
+	migrateTitleKeys(m.tags.Titles, m.groups)
+	return nil
+}
+
+// runExport 实现 mytui export [-copy 目录] <播放列表> [输出.m3u8]
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	copyDir := fs.String("copy", "", "把音频另存到该目录，播放列表指向另存的文件")
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return fmt.Errorf("用法: mytui export [-copy 目录] <播放列表> [输出.m3u8]")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	m, err := loadLibrary()
+	if err != nil {
+		return err
+	}
+	name := fs.Arg(0)
+	var pl *userdata.Playlist
+	for i := range m.playlists {
+		if m.playlists[i].Name == name {
+			pl = &m.playlists[i]
+		}
+	}
+	if pl == nil {
+		return fmt.Errorf("没有名为「%s」的播放列表", name)
+	}
+	out := safeFileName(name) + ".m3u8"
+	if fs.NArg() == 2 {
+		out = fs.Arg(1)
+	}
+	cids := pl.Items
+	if pl.Smart() {
+		if err := m.loadQueryData(); err != nil {
+			return err
+		}
+		cids = m.smartItems(m.groups, *pl)
+	}
+	skipped, err := exportM3UFile(out, cfg.Root, referencedItems(cids, libraryItems(m.groups)), *copyDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已导出 %d 首到 %s\n", len(cids)-len(skipped), out)
+	for _, s := range skipped {
+		fmt.Println("❗ 找不到音频: " + s)
+	}
+	return nil
+}
+
+func exportM3UFile(path, root string, items []Item, copyDir string) ([]string, error) {
+	if copyDir != "" {
+		if err := os.MkdirAll(copyDir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	skipped, err := writeM3U(f, items, copyDir, func(cid uint64) (string, error) {
+		return library.FindAudio(root, cid)
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return skipped, err
+}
+
+// runImport 实现 mytui import [-name 名称] <文件.m3u8>
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	name := fs.String("name", "", "播放列表名称（默认取文件名）")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("用法: mytui import [-name 名称] <文件.m3u8>")
+	}
+	m, err := loadLibrary()
+	if err != nil {
+		return err
+	}
+	pl, unmatched, err := importM3UFile(fs.Arg(0), *name, libraryItems(m.groups), m.playlists)
+	if err != nil {
+		return err
+	}
+	if err := userdata.SavePlaylists(append(m.playlists, pl)); err != nil {
+		return err
+	}
+	fmt.Printf("已导入「%s」：%d 首\n", pl.Name, len(pl.Items))
+	for _, u := range unmatched {
+		fmt.Println("❗ 未匹配: " + u)
+	}
+	return nil
+}
+
+func importM3UFile(path, name string, lib map[uint64]Item, playlists []userdata.Playlist) (userdata.Playlist, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return userdata.Playlist{}, nil, err
+	}
+	defer f.Close()
+	entries, err := parseM3U(f)
+	if err != nil {
+		return userdata.Playlist{}, nil, fmt.Errorf("读取 %s 失败: %w", path, err)
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	for _, pl := range playlists {
+		if pl.Name == name {
+			return userdata.Playlist{}, nil, fmt.Errorf("已有同名播放列表: %s", name)
+		}
+	}
+	cids, unmatched := matchM3U(entries, lib)
+	return userdata.Playlist{Name: name, Items: cids}, unmatched, nil
+}
+
+// ========== TUI 中导入 / 导出 ==========
+type m3uExportedMsg struct {
+	path    string
+	count   int
+	skipped []string
+	err     error
+}
+
+// openExportPlaylist 导出光标所在的播放列表
+func (m *model) openExportPlaylist() tea.Cmd {
+	if len(m.visibleNodes) == 0 {
+		return nil
+	}
+	node := m.visibleNodes[m.cursor]
+	i, ok := m.playlistAt(node)
+	if !ok || node.Type != NodeTitle {
+		m.pb.status = "请先选中一个播放列表"
+		return nil
+	}
+	m.promptIdx = i
+	return m.openPromptWith(PromptExportPlaylist, "导出到: ", safeFileName(m.playlists[i].Name)+".m3u8")
+}
+
+func (m *model) exportPlaylist(i int, path string) tea.Cmd {
+	if path == "" || i >= len(m.playlists) {
+		return nil
+	}
+	items := m.sectionGroup(sectionPlaylists).Titles[i].Items
+	if pl := m.playlists[i]; pl.Smart() {
+		// 目录树中的智能播放列表可能是打开时算的，导出前按当前的统计、评分和标签重新计算
+		lib := m.libraryGroups()
+		items = referencedItems(m.smartItems(lib, pl), libraryItems(lib))
+	}
+	return m.openExportCopy(items, path)
+}
+
+// exportMarked 导出标记的曲目
+func (m *model) exportMarked(path string) tea.Cmd {
+	if path == "" {
+		return nil
+	}
+	items := referencedItems(m.targetCIDs(), libraryItems(m.libraryGroups()))
+	m.clearMarks()
+	return m.openExportCopy(items, path)
+}
+
+// openExportCopy 询问是否把音频另存到目录：缓存中的 m4s 在其他播放器里通常无法直接播放
+func (m *model) openExportCopy(items []Item, path string) tea.Cmd {
+	m.pendingItems = items
+	m.promptPath = path
+	cmd := m.openPrompt(PromptExportCopy, "另存音频到目录（留空则指向缓存中的 m4s）: ")
+	m.promptHint = "m4s 只适合在本机用 mpv / VLC 播放，拷到其他设备请另存为 .m4a"
+	return cmd
+}
+
+func (m *model) exportItems(copyDir string) tea.Cmd {
+	items := m.pendingItems
+	path := expandHome(m.promptPath)
+	copyDir = expandHome(copyDir)
+	m.pendingItems = nil
+	root := m.cfg.Root
+	m.pb.status = "正在导出…"
+	return func() tea.Msg {
+		skipped, err := exportM3UFile(path, root, items, copyDir)
+		return m3uExportedMsg{path: path, count: len(items) - len(skipped), skipped: skipped, err: err}
+	}
+}
+
+func (m *model) onM3UExported(msg m3uExportedMsg) {
+	if msg.err != nil {
+		m.pb.status = "❗ " + msg.err.Error()
+		return
+	}
+	m.pb.status = fmt.Sprintf("已导出 %d 首到 %s", msg.count, msg.path)
+	if len(msg.skipped) > 0 {
+		m.pb.status += fmt.Sprintf("，%d 首找不到音频", len(msg.skipped))
+	}
+}
+
+func (m *model) importPlaylist(path string) {
+	if path == "" {
+		return
+	}
+	pl, unmatched, err := importM3UFile(expandHome(path), "", libraryItems(m.libraryGroups()), m.playlists)
+	if err != nil {
+		m.pb.status = "❗ " + err.Error()
+		return
+	}
+	m.playlists = append(m.playlists, pl)
+	if !m.savePlaylists() {
+		return
+	}
+	m.pb.status = fmt.Sprintf("已导入「%s」：%d 首", pl.Name, len(pl.Items))
+	if len(unmatched) > 0 {
+		m.pb.status += fmt.Sprintf("，%d 首未匹配（如 %s）", len(unmatched), unmatched[0])
+	}
+}
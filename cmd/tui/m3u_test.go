@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestM3URoundTrip(t *testing.T) {
+	const whole, medley = 1001, 2002
+	lib := map[uint64]Item{
+		whole:                                  {Title: "单曲", CID: whole, Duration: 200},
+		segmentID(medley, segmentKey(0)):       {Title: "01 开场", CID: segmentID(medley, segmentKey(0)), Duration: 95},
+		segmentID(medley, segmentKey(95.1234)): {Title: "02 第二首", CID: segmentID(medley, segmentKey(95.1234)), Start: 95.1234, End: 301.5, Duration: 206},
+		segmentID(medley, segmentKey(301.5)):   {Title: "03 第三首", CID: segmentID(medley, segmentKey(301.5)), Start: 301.5, Duration: 120},
+	}
+	order := []uint64{
+		segmentID(medley, segmentKey(301.5)),
+		whole,
+		segmentID(medley, segmentKey(95.1234)),
+		segmentID(medley, segmentKey(0)),
+	}
+	find := func(cid uint64) (string, error) {
+		if cid != whole && cid != medley {
+			return "", fmt.Errorf("未找到音频文件: %d", cid)
+		}
+		return filepath.Join("/cache", fmt.Sprint(cid), "1-30280.m4s"), nil
+	}
+
+	var buf bytes.Buffer
+	skipped, err := writeM3U(&buf, referencedItems(order, lib), "", find)
+	if err != nil || len(skipped) > 0 {
+		t.Fatalf("writeM3U: skipped=%v err=%v", skipped, err)
+	}
+	entries, err := parseM3U(&buf)
+	if err != nil {
+		t.Fatalf("parseM3U: %v", err)
+	}
+	cids, unmatched := matchM3U(entries, lib)
+	if len(unmatched) > 0 {
+		t.Errorf("未匹配: %v", unmatched)
+	}
+	if !slices.Equal(cids, order) {
+		t.Errorf("往返后得到 %v，期望 %v", cids, order)
+	}
+}
+
+func TestMatchM3UEntry(t *testing.T) {
+	seg := segmentID(2002, segmentKey(95))
+	lib := map[uint64]Item{
+		1001: {Title: "单曲", CID: 1001},
+		seg:  {Title: "02 第二首", CID: seg, Start: 95.5},
+	}
+	tests := []struct {
+		name  string
+		entry m3uEntry
+		want  uint64
+		ok    bool
+	}{
+		{"按 CID 目录", m3uEntry{Path: "/cache/1001/1-30280.m4s"}, 1001, true},
+		{"分段按起点", m3uEntry{Path: "/cache/2002/1-30280.m4s", Start: 95.5}, seg, true},
+		{"起点在误差内", m3uEntry{Path: "/cache/2002/1-30280.m4s", Start: 95.5004}, seg, true},
+		{"起点超出误差", m3uEntry{Path: "/cache/2002/1-30280.m4s", Start: 95.501}, 0, false},
+		{"按标题", m3uEntry{Title: "单曲", Path: "/music/a.m4a"}, 1001, true},
+		{"按另存的文件名", m3uEntry{Path: "/music/003 02 第二首.m4a"}, seg, true},
+		{"无法匹配", m3uEntry{Title: "别的歌", Path: "/music/b.mp3"}, 0, false},
+	}
+	byName := map[string]uint64{"单曲": 1001, "02 第二首": seg}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchM3UEntry(tt.entry, lib, byName)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("matchM3UEntry = %d, %v，期望 %d, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -4,16 +4,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
 	"os/exec"
+	"os/signal"
 	"strings"
 	"syscall"
 	"time"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ayazumi/biliCLI/internal/config"
+	treemodel "github.com/ayazumi/biliCLI/internal/model"
+	"github.com/ayazumi/biliCLI/internal/player"
+	"github.com/ayazumi/biliCLI/internal/tags"
+	"github.com/ayazumi/biliCLI/internal/userdata"
 )
 
 const TreeJSONPath = "buildtree/tree.json"
@@ -22,21 +28,26 @@ type buildFinishedMsg struct{ err error }
 
 // ========== 数据结构 ==========
 type Item struct {
-	Title string `json:"title"`
-	CID   uint64 `json:"cid"`
+	Title    string  `json:"title"`
+	CID      uint64  `json:"cid"`
+	Duration uint32  `json:"duration"` // 秒
+	Start    float64 `json:"-"`        // 分段：在整个视频中的起点，秒
+	End      float64 `json:"-"`        // 分段：在整个视频中的终点，0 为视频结尾
 }
 
 type TitleNode struct {
-	Name  string `json:"name"`
-	P     *uint32
-	Items []Item
-	Open  bool
+	Name   string `json:"name"`
+	P      *uint32
+	Items  []Item
+	Open   bool
+	source string // 收藏分组中引用的曲库标题：它在曲库中所在的分组
 }
 
 type GroupNode struct {
-	Name   string      `json:"name"`
-	Titles []TitleNode `json:"titles"`
-	Open   bool
+	Name    string      `json:"name"`
+	Titles  []TitleNode `json:"titles"`
+	Open    bool
+	section section // 曲库或播放列表等虚拟分组
 }
 
 type NodeType int
@@ -48,13 +59,17 @@ const (
 )
 
 type TreeNode struct {
-	Type     NodeType
-	Depth    int
-	Name     string
-	CID      uint64
-	Expanded bool
+	Type               NodeType
+	Depth              int
+	Name               string
+	CID                uint64
+	Expanded           bool
 	groupIdx, titleIdx int
-	items    []Item
+	items              []Item
+	resume             userdata.Position // 仅 Item 节点：上次的播放进度
+	plays              int               // 仅 Item 节点：显示的播放次数，0 为不显示
+	rating             userdata.Rating   // 收藏和评分
+	tags               []string          // 节点自身的标签（不含继承的）
 }
 
 func (n TreeNode) Display() string {
@@ -67,7 +82,37 @@ func (n TreeNode) Display() string {
 			marker = "▶"
 		}
 	}
-	return fmt.Sprintf("%s%s %s", indent, marker, n.Name)
+	line := fmt.Sprintf("%s%s %s", indent, marker, n.Name) + ratingGlyphs(n.rating) + tagGlyphs(n.tags)
+	switch {
+	case n.resume.Finished:
+		line += " ✔"
+	case n.resume.InProgress():
+		line += " ◐ " + formatSeconds(n.resume.Pos)
+	}
+	if n.plays > 0 {
+		line += fmt.Sprintf(" ×%d", n.plays)
+	}
+	return line
+}
+
+// CIDs 返回节点下的全部曲目，顺序与目录树一致
+func (n TreeNode) CIDs() []uint64 {
+	if n.Type == NodeItem {
+		return []uint64{n.CID}
+	}
+	cids := make([]uint64, 0, len(n.items))
+	for _, item := range n.items {
+		cids = append(cids, item.CID)
+	}
+	return cids
+}
+
+func formatSeconds(sec float64) string {
+	s := int(sec)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s%3600/60, s%60)
+	}
+	return fmt.Sprintf("%02d:%02d", s/60, s%60)
 }
 
 // ========== 加载 tree.json ==========
@@ -80,12 +125,13 @@ func loadTree() []GroupNode {
 	var rawGroups []struct {
 		Name   string `json:"name"`
 		Titles []struct {
-			Name  string `json:"name"`
-			P     *uint32
-			Tabs  []struct {
+			Name string `json:"name"`
+			P    *uint32
+			Tabs []struct {
 				Name  string `json:"name"`
 				Items []struct {
-					CID uint64 `json:"cid"`
+					CID      uint64 `json:"cid"`
+					Duration uint32 `json:"duration"`
 				} `json:"items"`
 			} `json:"tabs"`
 		} `json:"titles"`
@@ -102,8 +148,9 @@ func loadTree() []GroupNode {
 			for _, tab := range rt.Tabs {
 				if len(tab.Items) > 0 {
 					items = append(items, Item{
-						Title: tab.Name,
-						CID:   tab.Items[0].CID,
+						Title:    tab.Name,
+						CID:      tab.Items[0].CID,
+						Duration: tab.Items[0].Duration,
 					})
 				}
 			}
@@ -138,6 +185,11 @@ type PlayMode int
 const (
 	PlayModeSequential PlayMode = iota
 	PlayModeShuffle
+	PlayModeRepeatOne  // 单曲循环
+	PlayModeRepeatAll  // 列表循环
+	PlayModeShuffleBag // 随机且全部播完前不重复，袋子跨会话保留
+	PlayModeWeighted   // 按评分、播放次数和上次播放时间加权抽取
+	playModeCount
 )
 
 func (p PlayMode) String() string {
@@ -146,29 +198,22 @@ func (p PlayMode) String() string {
 		return "顺序"
 	case PlayModeShuffle:
 		return "随机"
+	case PlayModeRepeatOne:
+		return "单曲循环"
+	case PlayModeRepeatAll:
+		return "列表循环"
+	case PlayModeShuffleBag:
+		return "随机不重复"
+	case PlayModeWeighted:
+		return "加权随机"
 	default:
 		return "未知"
 	}
 }
 
-// ========== 播放逻辑 ==========
-func playCIDs(cids []uint64, mode PlayMode) tea.Cmd {
-	return func() tea.Msg {
-		list := make([]uint64, len(cids))
-		copy(list, cids)
-		if mode == PlayModeShuffle {
-			rand.Seed(time.Now().UnixNano())
-			rand.Shuffle(len(list), func(i, j int) { list[i], list[j] = list[j], list[i] })
-		}
-		for _, cid := range list {
-			cmd := exec.Command("./play", fmt.Sprintf("%d", cid))
-			cmd.Stdin = os.Stdin
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-			cmd.Run()
-		}
-		return nil
-	}
+// Next 返回 m 键切换到的下一个模式
+func (p PlayMode) Next() PlayMode {
+	return (p + 1) % playModeCount
 }
 
 // ========== 状态 ==========
@@ -179,11 +224,40 @@ const (
 	StateBuilding
 	StateTUI
 	StateSearchInput
+	StateResumePrompt // 询问是否从上次的位置继续
+	StatePrompt       // 通用的单行输入（如书签名）
+	StateChoose       // 通用的列表选择（如输出设备）
+	StateSplit        // 调整自动分段的建议
+	StateQueue        // 播放队列面板
+	StateHistory      // 收听记录面板
+	StateKeys         // 全部快捷键
+)
+
+// promptKind 区分 StatePrompt 的用途
+type promptKind int
+
+const (
+	PromptBookmark promptKind = iota
+	PromptTrim
+	PromptSegments
+	PromptSplitTitle
+	PromptSplitTime
+	PromptNewPlaylist
+	PromptRenamePlaylist
+	PromptExportPlaylist
+	PromptImportPlaylist
+	PromptExportMarked
+	PromptExportCopy
+	PromptTags
+	PromptTagFilter
+	PromptSmartName
+	PromptSmartQuery
 )
 
 // ========== Model ==========
 type model struct {
 	state        state
+	cfg          config.Config
 	groups       []GroupNode
 	visibleNodes []TreeNode
 	allNodes     []TreeNode
@@ -191,6 +265,62 @@ type model struct {
 	viewport     viewport.Model
 	playMode     PlayMode
 	buildError   error
+	positions    map[uint64]userdata.Position
+	loudness     map[uint64]userdata.Loudness
+	trims        map[uint64]userdata.Trim
+	segments     map[uint64][]userdata.Segment
+	playlists    []userdata.Playlist
+	playlistsErr error // 读取失败时不再保存，避免覆盖原文件
+	stats        map[uint64]userdata.Stats
+	ratings      userdata.Ratings
+	ratingsErr   error // 读取失败时不再保存
+	tags         userdata.Tags
+	tagsErr      error     // 读取失败时不再保存
+	tagFilter    tags.Expr // 为 nil 时不筛选
+	tagQuery     string
+	showCounts   bool // 目录树中显示播放次数
+	gainMode     GainMode
+	eqPreset     string
+	titleOf      map[uint64][2]int
+	sleep        *sleepTimer
+	sleepTicking bool
+	pb           *playback
+	resumeBack   state // 回答"是否继续"后回到的状态
+
+	// 通用输入框
+	promptInput textinput.Model
+	promptFor   promptKind
+	promptCID   uint64   // PromptTrim / PromptSegments：正在编辑的条目
+	promptIdx   int      // PromptRenamePlaylist / PromptExportPlaylist：正在编辑的播放列表
+	promptBack  state    // 输入结束后回到的状态
+	promptNode  TreeNode // PromptTags：正在编辑的节点
+	promptName  string   // PromptSmartQuery：新建的智能播放列表名称
+	promptPath  string   // PromptExportCopy：导出的 M3U8 路径
+	promptHint  string   // 显示在输入框下方的提示，如查询匹配的曲目数
+
+	// 通用列表选择
+	chooser      chooser
+	pendingCIDs  []uint64 // 等待加入播放列表的曲目
+	pendingItems []Item   // 等待导出的曲目
+
+	// 多选
+	selected     treemodel.Selection
+	visual       bool // 区间选择中
+	visualAnchor int
+
+	// 自动分段对话框
+	split        splitDialog
+	pendingSplit *splitDialog // 对话框打不开时先暂存的检测结果，不必重新检测
+
+	// 播放队列面板
+	queueCursor int
+	queueTop    int
+
+	// 收听记录面板
+	history       []historyPlay
+	historyRows   []historyRow
+	historyCursor int
+	historyTop    int
 
 	// 搜索相关
 	searchInput  textinput.Model // ← 使用 textinput
@@ -203,33 +333,90 @@ func newModel() model {
 	ti.Placeholder = "输入关键词..."
 	ti.Focus()
 
+	pi := textinput.New()
+
+	cfg, _ := config.Load()
 	m := model{
+		cfg:          cfg,
 		playMode:     PlayModeSequential,
+		gainMode:     parseGainMode(cfg.Loudness.Mode),
+		eqPreset:     cfg.EQ.Preset,
+		sleep:        newSleepTimer(),
+		pb:           newPlayback(cfg, time.Now().UnixNano()),
 		lastMatchIdx: -1,
+		showCounts:   cfg.Stats.ShowCounts,
+		selected:     treemodel.NewSelection(),
 		searchInput:  ti,
+		promptInput:  pi,
 	}
 	if _, err := os.Stat(TreeJSONPath); err == nil {
 		m.state = StateTUI
 		m.groups = loadTree()
-		m.rebuildAllNodes()
-		m.rebuildVisible()
+		m.segments, _ = userdata.LoadSegments()
+		expandSegments(m.groups, m.segments)
+		m.positions, _ = userdata.LoadPositions()
+		m.loudness, _ = userdata.LoadLoudness()
+		m.trims, _ = userdata.LoadTrims()
+		m.playlists, m.playlistsErr = userdata.LoadPlaylists()
+		m.stats, _ = userdata.LoadStats()
+		m.ratings, m.ratingsErr = userdata.LoadRatings()
+		migrateTitleKeys(m.ratings.Titles, m.groups)
+		m.tags, m.tagsErr = userdata.LoadTags()
+		migrateTitleKeys(m.tags.Titles, m.groups)
+		m.refreshVirtualGroups()
 		m.initViewport()
+		m.restoreQueue()
+		if m.playlistsErr != nil {
+			m.pb.status = readOnlyStatus(m.playlistsErr, "播放列表")
+		}
+		if m.ratingsErr != nil {
+			m.pb.status = readOnlyStatus(m.ratingsErr, "收藏和评分")
+		}
+		if m.tagsErr != nil {
+			m.pb.status = readOnlyStatus(m.tagsErr, "标签")
+		}
 	} else {
 		m.state = StateBuildPrompt
 	}
 	return m
 }
 
+// readOnlyStatus 是用户数据读取失败、本次运行不再保存时的提示
+func readOnlyStatus(err error, what string) string {
+	return "❗ " + err.Error() + "，" + what + "的修改不会保存"
+}
+
 func (m *model) initViewport() {
 	v := viewport.New(80, 10)
 	m.viewport = v
 }
 
+// indexTitles 为曲库中的曲目（及分段的源视频）建立到所在标题的索引
+func (m *model) indexTitles() {
+	m.titleOf = make(map[uint64][2]int)
+	for gi, g := range m.groups {
+		if g.section != sectionLibrary {
+			continue
+		}
+		for ti, t := range g.Titles {
+			for _, item := range t.Items {
+				m.titleOf[item.CID] = [2]int{gi, ti}
+				m.titleOf[sourceCID(item.CID)] = [2]int{gi, ti}
+			}
+		}
+	}
+}
+
 func (m *model) rebuildAllNodes() {
 	var nodes []TreeNode
+	m.indexTitles()
 	for gi, g := range m.groups {
+		titles := m.shownTitles(g)
+		if len(titles) == 0 && m.tagFilter != nil {
+			continue
+		}
 		var groupItems []Item
-		for _, t := range g.Titles {
+		for _, t := range titles {
 			groupItems = append(groupItems, t.Items...)
 		}
 		nodes = append(nodes, TreeNode{
@@ -238,8 +425,10 @@ func (m *model) rebuildAllNodes() {
 			Name:     g.Name,
 			groupIdx: gi,
 			items:    groupItems,
+			tags:     m.groupTags(g),
 		})
-		for ti, t := range g.Titles {
+		for _, t := range titles {
+			ti := t.idx
 			nodes = append(nodes, TreeNode{
 				Type:     NodeTitle,
 				Depth:    1,
@@ -247,6 +436,8 @@ func (m *model) rebuildAllNodes() {
 				groupIdx: gi,
 				titleIdx: ti,
 				items:    t.Items,
+				rating:   m.titleRating(g, t.TitleNode),
+				tags:     m.titleTags(g, t.TitleNode),
 			})
 			for _, item := range t.Items {
 				nodes = append(nodes, TreeNode{
@@ -256,6 +447,10 @@ func (m *model) rebuildAllNodes() {
 					CID:      item.CID,
 					groupIdx: gi,
 					titleIdx: ti,
+					resume:   m.positions[item.CID],
+					plays:    m.shownPlays(item.CID),
+					rating:   m.ratings.Items[item.CID],
+					tags:     m.tags.Items[item.CID],
 				})
 			}
 		}
@@ -266,8 +461,12 @@ func (m *model) rebuildAllNodes() {
 func (m *model) rebuildVisible() {
 	var nodes []TreeNode
 	for gi, g := range m.groups {
+		titles := m.shownTitles(g)
+		if len(titles) == 0 && m.tagFilter != nil {
+			continue
+		}
 		var groupItems []Item
-		for _, t := range g.Titles {
+		for _, t := range titles {
 			groupItems = append(groupItems, t.Items...)
 		}
 		nodes = append(nodes, TreeNode{
@@ -277,11 +476,13 @@ func (m *model) rebuildVisible() {
 			Expanded: g.Open,
 			groupIdx: gi,
 			items:    groupItems,
+			tags:     m.groupTags(g),
 		})
 		if !g.Open {
 			continue
 		}
-		for ti, t := range g.Titles {
+		for _, t := range titles {
+			ti := t.idx
 			nodes = append(nodes, TreeNode{
 				Type:     NodeTitle,
 				Depth:    1,
@@ -290,6 +491,8 @@ func (m *model) rebuildVisible() {
 				groupIdx: gi,
 				titleIdx: ti,
 				items:    t.Items,
+				rating:   m.titleRating(g, t.TitleNode),
+				tags:     m.titleTags(g, t.TitleNode),
 			})
 			if !t.Open {
 				continue
@@ -302,16 +505,29 @@ func (m *model) rebuildVisible() {
 					CID:      item.CID,
 					groupIdx: gi,
 					titleIdx: ti,
+					resume:   m.positions[item.CID],
+					plays:    m.shownPlays(item.CID),
+					rating:   m.ratings.Items[item.CID],
+					tags:     m.tags.Items[item.CID],
 				})
 			}
 		}
 	}
 	m.visibleNodes = nodes
-	if m.state == StateTUI || m.state == StateSearchInput {
+	if m.showsTree() {
 		m.refreshViewport()
 	}
 }
 
+// showsTree 表示当前状态下目录树处于活动状态
+func (m *model) showsTree() bool {
+	switch m.state {
+	case StateTUI, StateSearchInput, StateResumePrompt, StatePrompt, StateChoose, StateSplit, StateQueue, StateHistory, StateKeys:
+		return true
+	}
+	return false
+}
+
 func (m *model) refreshViewport() {
 	content := m.renderContent()
 	m.viewport.SetContent(content)
@@ -330,7 +546,7 @@ func (m *model) renderContent() string {
 		} else {
 			line = "  " + line
 		}
-		lines = append(lines, line)
+		lines = append(lines, m.renderRow(i, line))
 	}
 	return strings.Join(lines, "\n")
 }
@@ -373,6 +589,12 @@ func (m *model) jumpToAllNode(allIdx int) {
 	m.refreshViewport()
 }
 
+// searchMatches 判断 allNodes[i] 是否匹配搜索；只搜索曲库，不落到播放列表等虚拟分组中的副本上
+func (m *model) searchMatches(i int, lowerQuery string) bool {
+	node := m.allNodes[i]
+	return m.sectionOf(node) == sectionLibrary && strings.Contains(strings.ToLower(node.Name), lowerQuery)
+}
+
 func (m *model) searchAndJump(query string) {
 	if query == "" {
 		m.lastSearch = ""
@@ -382,8 +604,8 @@ func (m *model) searchAndJump(query string) {
 
 	lowerQuery := strings.ToLower(query)
 	found := false
-	for i, node := range m.allNodes {
-		if strings.Contains(strings.ToLower(node.Name), lowerQuery) {
+	for i := range m.allNodes {
+		if m.searchMatches(i, lowerQuery) {
 			m.lastSearch = query
 			m.lastMatchIdx = i
 			found = true
@@ -398,15 +620,106 @@ func (m *model) searchAndJump(query string) {
 	}
 }
 
+// ========== 通用输入框 ==========
+func (m *model) openPrompt(kind promptKind, label string) tea.Cmd {
+	return m.openPromptWith(kind, label, "")
+}
+
+// openPromptWith 打开预先填好 value 的输入框，结束后回到当前状态
+func (m *model) openPromptWith(kind promptKind, label, value string) tea.Cmd {
+	m.promptFor = kind
+	m.promptInput.Prompt = label
+	m.promptInput.SetValue(value)
+	m.promptInput.CursorEnd()
+	m.promptHint = ""
+	m.promptBack = m.state
+	m.state = StatePrompt
+	return m.promptInput.Focus()
+}
+
+func (m *model) submitPrompt(value string) tea.Cmd {
+	value = strings.TrimSpace(value)
+	switch m.promptFor {
+	case PromptBookmark:
+		m.saveBookmark(value)
+	case PromptTrim:
+		m.submitTrim(m.promptCID, value)
+	case PromptSegments:
+		m.submitSegments(m.promptCID, value)
+	case PromptSplitTitle:
+		m.submitSplitTitle(value)
+	case PromptSplitTime:
+		m.submitSplitTime(value)
+	case PromptNewPlaylist:
+		m.createPlaylist(value)
+	case PromptRenamePlaylist:
+		m.renamePlaylist(m.promptIdx, value)
+	case PromptExportPlaylist:
+		return m.exportPlaylist(m.promptIdx, value)
+	case PromptImportPlaylist:
+		m.importPlaylist(value)
+	case PromptExportMarked:
+		return m.exportMarked(value)
+	case PromptExportCopy:
+		return m.exportItems(value)
+	case PromptTags:
+		m.submitTags(value)
+	case PromptTagFilter:
+		m.setTagFilter(value)
+	case PromptSmartName:
+		return m.submitSmartName(value)
+	case PromptSmartQuery:
+		m.submitSmartQuery(value)
+	}
+	return nil
+}
+
+// helpView 是目录树下方的简短提示，全部快捷键按 ? 查看
 func (m model) helpView() string {
-	modeStr := m.playMode.String()
-	return fmt.Sprintf("\n\nh=收起  l=展开  j/k=上下  Enter=播放  m=切换模式(%s)  q=退出  b=同步列表  /=搜索（n=next）", modeStr)
+	help := fmt.Sprintf("\n\nh/l=收起/展开  j/k=上下  Enter=播放  p=暂停  x=下一首  m=切换模式(%s)  /=搜索  Tab=队列  q=退出  ?=全部快捷键", m.playMode)
+	if m.visual {
+		help += "  -- 区间选择 --"
+	}
+	if n := m.selected.Len(); n > 0 {
+		help += fmt.Sprintf("  ✔ 已选 %d 首", n)
+	}
+	if m.tagFilter != nil {
+		help += "  🏷 " + m.tagQuery
+	}
+	if m.pb.radioOn {
+		help += "  📻 电台:开"
+	}
+	if status := m.sleep.String(); status != "" {
+		help += "  " + status
+	}
+	return help
+}
+
+// keysView 列出全部快捷键，按 ? 打开
+func (m model) keysView() string {
+	lines := []string{
+		"浏览    h/l=收起/展开  j/k=上下  /=搜索（n=下一个）  b=同步列表  q=退出",
+		fmt.Sprintf("播放    Enter=播放  i=下一首播放  a=加入队列  p=暂停  x/>=下一首  <=上一首  s=停止  m=切换模式(%s)  R=电台  -=移除下一首", m.playMode),
+		"循环    [ ]=AB点  \\=清除循环  '=存书签  `=书签  z=睡眠定时",
+		fmt.Sprintf("音频    o=输出设备  g=音量均衡(%s)  e=均衡器(%s)  T=裁剪静音  C=分段  A=自动分段", m.gainMode, eqLabel(m.eqPreset)),
+		"面板    Tab=队列  H=收听记录  #=播放次数",
+		"整理    f=收藏  1-5/0=评分/清除  t=标签  F=标签筛选  空格=标记  v=区间选择  Esc=取消标记",
+		"列表    +=加入播放列表  S=智能播放列表  r=重命名列表  D=删除列表/条目  E/I=导出/导入 M3U8",
+	}
+	return "\n快捷键\n\n" + strings.Join(lines, "\n") + "\n\n（按 ? 或 Esc 返回）"
 }
 
 // ========== Bubble Tea ==========
 func (m model) Init() tea.Cmd { return nil }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	m = next.(model)
+	m.openPendingSplit()
+	return m, cmd
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case buildFinishedMsg:
 		if msg.err != nil {
@@ -414,15 +727,39 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.state = StateBuildPrompt
 			return m, nil
 		}
+		// 重启前停止后台播放，否则 ffplay 等子进程会成为无人管理的孤儿
+		m.shutdown()
 		_ = syscall.Exec(os.Args[0], os.Args, os.Environ())
 		return m, nil
 
+	case sleepTickMsg:
+		m.sleep.expireIdle()
+		if !m.sleep.active() {
+			m.sleepTicking = false
+			return m, nil
+		}
+		return m, sleepTick()
+
+	case m3uExportedMsg:
+		m.onM3UExported(msg)
+		return m, nil
+
+	case splitSuggestMsg:
+		m.onSplitSuggest(msg)
+		return m, nil
+
+	case trackReadyMsg:
+		return m, m.onTrackReady(msg)
+
+	case playbackTickMsg:
+		return m, m.onPlaybackTick()
+
 	case tea.KeyMsg:
 		switch m.state {
 		case StateBuildPrompt:
 			switch msg.String() {
 			case "b", "B":
-				m.state = StateTUI 
+				m.state = StateTUI
 				return m, buildTreeCmd()
 			case "q", "ctrl+c":
 				return m, tea.Quit
@@ -445,14 +782,75 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, cmd // ← 必须返回 cmd！
 
+		case StatePrompt:
+			switch msg.String() {
+			case "enter":
+				m.state = m.promptBack
+				m.promptInput.Blur()
+				return m, m.submitPrompt(m.promptInput.Value())
+			case "esc":
+				m.state = m.promptBack
+				m.promptInput.Blur()
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.promptInput, cmd = m.promptInput.Update(msg)
+			if m.promptFor == PromptSmartQuery {
+				m.updateQueryHint()
+			}
+			return m, cmd
+
+		case StateChoose:
+			return m, m.chooserKey(msg.String())
+
+		case StateSplit:
+			return m, m.splitKey(msg.String())
+
+		case StateQueue:
+			return m, m.queueKey(msg.String())
+
+		case StateHistory:
+			return m, m.historyKey(msg.String())
+
+		case StateKeys:
+			switch msg.String() {
+			case "?", "esc", "q":
+				m.state = StateTUI
+			}
+
+		case StateResumePrompt:
+			switch msg.String() {
+			case "y", "Y", "enter":
+				return m, m.answerResume(true)
+			case "n", "N", "esc":
+				return m, m.answerResume(false)
+			}
+
 		case StateTUI:
 			switch key := msg.String(); key {
 			case "b", "B":
 				m.state = StateTUI
 				return m, buildTreeCmd()
 			case "q", "ctrl+c":
-				exec.Command("pkill", "-f", "play").Run()
-				return m, tea.Quit
+				return m, m.quit()
+			case "?":
+				m.state = StateKeys
+			case "tab":
+				m.openQueue()
+			case "#":
+				m.toggleCounts()
+			case "f":
+				m.toggleFavourite()
+			case "t":
+				return m, m.openTagEditor()
+			case "F":
+				return m, m.openTagFilter()
+			case "S":
+				return m, m.openSmartPlaylist()
+			case "0", "1", "2", "3", "4", "5":
+				m.setStars(int(key[0] - '0'))
+			case "H":
+				m.openHistory()
 			case "j":
 				if m.cursor < len(m.visibleNodes)-1 {
 					m.cursor++
@@ -464,6 +862,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.refreshViewport()
 				}
 			case "l":
+				m.refreshSmart()
+				if len(m.visibleNodes) == 0 {
+					break
+				}
 				node := m.visibleNodes[m.cursor]
 				if node.Type == NodeGroup {
 					m.groups[node.groupIdx].Open = true
@@ -549,29 +951,110 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 
 			case "enter":
-				node := m.visibleNodes[m.cursor]
-				var cids []uint64
-				if node.Type == NodeItem {
-					cids = []uint64{node.CID}
-				} else {
-					for _, item := range node.items {
-						cids = append(cids, item.CID)
-					}
-				}
 				m.lastSearch = ""
 				m.lastMatchIdx = -1
-				if len(cids) > 0 {
-					return m, playCIDs(cids, m.playMode)
+				m.refreshSmart()
+				return m, m.playTargets()
+
+			case "i":
+				m.refreshSmart()
+				m.enqueueTargets(true)
+
+			case "a":
+				m.refreshSmart()
+				m.enqueueTargets(false)
+
+			case " ":
+				m.toggleMark()
+
+			case "v":
+				m.toggleVisual()
+
+			case "esc":
+				m.cancelMarks()
+
+			case "+":
+				m.openAddToPlaylist()
+
+			case "r":
+				return m, m.openRenamePlaylist()
+
+			case "D":
+				m.deleteFromPlaylists()
+
+			case "E":
+				if m.selected.Len() > 0 {
+					return m, m.openPromptWith(PromptExportMarked, "导出到: ", "选中曲目.m3u8")
 				}
+				return m, m.openExportPlaylist()
 
-			case "m":
-				if m.playMode == PlayModeSequential {
-					m.playMode = PlayModeShuffle
-				} else {
-					m.playMode = PlayModeSequential
+			case "I":
+				return m, m.openPrompt(PromptImportPlaylist, "导入 M3U8 文件: ")
+
+			case "p":
+				return m, m.togglePause()
+
+			case "x", ">":
+				return m, m.skipTrack()
+
+			case "<":
+				return m, m.prevTrack()
+
+			case "s":
+				m.stopQueue()
+
+			case "R":
+				m.pb.radioOn = !m.pb.radioOn
+
+			case "-":
+				m.removeNext()
+
+			case "o":
+				m.openDeviceChooser()
+
+			case "g":
+				m.cycleGainMode()
+
+			case "T":
+				return m, m.openTrimPrompt()
+
+			case "e":
+				m.openEQChooser()
+
+			case "C":
+				return m, m.openSegmentsPrompt()
+
+			case "A":
+				return m, m.startSplitSuggest()
+
+			case "[":
+				m.setLoopA()
+
+			case "]":
+				m.setLoopB()
+
+			case "\\":
+				m.pb.clearLoop()
+
+			case "'":
+				if m.pb.looping() {
+					return m, m.openPrompt(PromptBookmark, "书签名: ")
 				}
+
+			case "`":
+				m.nextBookmark()
+
+			case "m":
+				m.playMode = m.playMode.Next()
 				m.refreshViewport()
 
+			case "z":
+				m.sleep.cycle()
+				if m.sleep.active() && !m.sleepTicking {
+					m.sleepTicking = true
+					return m, sleepTick()
+				}
+
 			case "/":
 				m.state = StateSearchInput
 				m.searchInput.SetValue("")
@@ -586,7 +1069,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				start := m.lastMatchIdx + 1
 				found := false
 				for i := start; i < len(m.allNodes); i++ {
-					if strings.Contains(strings.ToLower(m.allNodes[i].Name), lowerQuery) {
+					if m.searchMatches(i, lowerQuery) {
 						m.lastMatchIdx = i
 						found = true
 						break
@@ -594,7 +1077,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				if !found {
 					for i := 0; i < start; i++ {
-						if strings.Contains(strings.ToLower(m.allNodes[i].Name), lowerQuery) {
+						if m.searchMatches(i, lowerQuery) {
 							m.lastMatchIdx = i
 							found = true
 							break
@@ -615,10 +1098,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.WindowSizeMsg:
-		if m.state == StateTUI || m.state == StateSearchInput {
+		if m.showsTree() {
 			helpHeight := 3
 			m.viewport.Width = msg.Width
-			m.viewport.Height = msg.Height - helpHeight
+			m.viewport.Height = msg.Height - helpHeight - nowPlayingHeight - 1
 			m.refreshViewport()
 		}
 	}
@@ -638,17 +1121,72 @@ func (m model) View() string {
 		return "正在同步列表...\n"
 	case StateSearchInput:
 		return "\n搜索: " + m.searchInput.View() + "\n\n（按 Enter 搜索，Esc 取消）"
+	case StatePrompt:
+		view := "\n" + m.promptInput.View()
+		if m.promptHint != "" {
+			view += "\n" + m.promptHint
+		}
+		return view + "\n\n（按 Enter 确认，Esc 取消）"
+	case StateChoose:
+		return m.chooserView()
+	case StateSplit:
+		return m.splitView()
+	case StateQueue:
+		return m.queueView() + "\n" + m.nowPlayingView() + m.queueHelpView()
+	case StateHistory:
+		return m.historyView() + "\n" + m.nowPlayingView() + m.historyHelpView()
+	case StateResumePrompt:
+		pos := m.positions[m.pb.pending.CID]
+		return m.viewport.View() + "\n" + m.nowPlayingView() +
+			fmt.Sprintf("\n\n%s 上次播放到 %s，是否继续？[Y/n]", m.pb.pending.Name, formatSeconds(pos.Pos))
+	case StateKeys:
+		return m.keysView() + "\n" + m.nowPlayingView()
 	case StateTUI:
-		return m.viewport.View() + m.helpView()
+		return m.viewport.View() + "\n" + m.nowPlayingView() + m.helpView()
 	default:
 		return "未知状态"
 	}
 }
 
+// 不进入 TUI 的子命令：mytui <名称> [参数]
+var subcommands = map[string]func(args []string) error{
+	"analyze":       runAnalyze,
+	"segments":      runSegments,
+	"split-suggest": runSplitSuggest,
+	"export":        runExport,
+	"import":        runImport,
+}
+
 func main() {
-	defer exec.Command("pkill", "-f", "play").Run()
-	p := tea.NewProgram(newModel(), tea.WithAltScreen())
+	// 清理上次异常退出残留的子进程；launch 在 TUI 退出后以 --cleanup 调用
+	player.CleanupStale(playerRunDir())
+	if len(os.Args) > 1 && os.Args[1] == "--cleanup" {
+		return
+	}
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
+	m := newModel()
+	// 正常退出、SIGINT / SIGTERM（Bubble Tea 会转成退出）和 panic 时结束播放进程组
+	defer m.pb.player.Stop()
+	p := tea.NewProgram(m, tea.WithAltScreen())
+
+	// 终端被关闭（SIGHUP）时同样正常退出
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		<-hup
+		p.Quit()
+	}()
+
 	if _, err := p.Run(); err != nil {
+		m.pb.player.Stop()
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,700 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ayazumi/biliCLI/internal/config"
+	"github.com/ayazumi/biliCLI/internal/library"
+	"github.com/ayazumi/biliCLI/internal/player"
+	"github.com/ayazumi/biliCLI/internal/shuffle"
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// ========== 后台播放 ==========
+// 播放在后台进行，所有播放按键都由 TUI 处理，播放时仍可浏览、搜索
+
+// 只为不短于 10 分钟的条目记录播放进度
+const resumeMinDuration = 10 * time.Minute
+
+const playbackTickInterval = 250 * time.Millisecond
+
+type playbackTickMsg struct{}
+
+// trackReadyMsg 在后台找到曲目的音频文件后发出
+type trackReadyMsg struct {
+	seq  int // 对应 playback.seq，过期的结果直接丢弃
+	cid  uint64
+	file string
+	trim *userdata.Trim // 本次新检测到的静音裁剪位置
+	err  error
+}
+
+type playback struct {
+	player   *player.Player
+	picker   *shuffle.Picker // 随机模式共用的随机源，只在启动时播种一次
+	pool     []uint64        // 本次播放范围，按目录树顺序
+	queue    []uint64        // 实际播放顺序
+	radio    map[uint64]bool // 由电台模式追加的曲目
+	radioOn  bool
+	index    int
+	seq      int // 每次切换曲目递增
+	ticking  bool
+	fading   bool
+	failed   int          // 连续找不到 / 无法播放的曲目数，达到 failureLimit 时停止
+	pending  player.Track // 等待回答"是否继续"的曲目
+	loopA    time.Duration
+	loopB    time.Duration // 均 >= 0 且 loopB > loopA 时循环
+	bmIdx    int           // ` 键轮换书签的位置
+	listened time.Duration // 本次收听实际播放的时长，不含暂停，不受 seek 和循环影响
+	listenAt time.Time     // 上次累计 listened 的时刻
+	status   string        // 最近一条提示
+}
+
+// newPlayback 创建播放状态，seed 为随机模式的种子
+func newPlayback(cfg config.Config, seed int64) *playback {
+	pb := &playback{
+		player: player.New(playerRunDir()),
+		radio:  make(map[uint64]bool),
+		picker: shuffle.NewPicker(seed, cfg.Shuffle.NoRepeatWindow),
+		loopA:  -1,
+		loopB:  -1,
+	}
+	pb.player.SetDevice(cfg.Audio.Device)
+	return pb
+}
+
+// playerRunDir 存放播放子进程组号的目录，用于异常退出后的清理
+func playerRunDir() string {
+	return userdata.Path("run")
+}
+
+// startListen 开始累计新一次收听的时长
+func (pb *playback) startListen() {
+	pb.listened = 0
+	pb.listenAt = time.Now()
+}
+
+// countListen 把上次累计以来没有暂停的时间计入本次收听
+func (pb *playback) countListen() {
+	now := time.Now()
+	if !pb.listenAt.IsZero() && !pb.player.Paused() {
+		pb.listened += now.Sub(pb.listenAt)
+	}
+	pb.listenAt = now
+}
+
+func (pb *playback) current() (uint64, bool) {
+	if pb.index < 0 || pb.index >= len(pb.queue) {
+		return 0, false
+	}
+	return pb.queue[pb.index], true
+}
+
+func (pb *playback) clearLoop() {
+	pb.loopA, pb.loopB = -1, -1
+}
+
+func (pb *playback) looping() bool {
+	return pb.loopA >= 0 && pb.loopB > pb.loopA
+}
+
+func playbackTick() tea.Cmd {
+	return tea.Tick(playbackTickInterval, func(time.Time) tea.Msg { return playbackTickMsg{} })
+}
+
+// resolveTrack 在后台查找音频文件（需要 ffprobe 探测，可能较慢）
+// 需要时顺带检测开头和结尾的静音（duration 为曲目时长，秒）
+// cfg 为模型持有的配置，不在每首曲目前重新读取
+func resolveTrack(cfg config.Config, seq int, cid uint64, detect bool, duration float64) tea.Cmd {
+	return func() tea.Msg {
+		file, err := library.FindAudio(cfg.Root, sourceCID(cid))
+		if err != nil {
+			return trackReadyMsg{seq: seq, cid: cid, err: err}
+		}
+		msg := trackReadyMsg{seq: seq, cid: cid, file: file}
+		if detect {
+			msg.trim = detectTrim(cfg, file, duration)
+		}
+		return msg
+	}
+}
+
+// playSequence 替换当前队列并开始播放
+func (m *model) playSequence(cids []uint64) tea.Cmd {
+	m.stopTrack()
+	m.pb.pool = append([]uint64(nil), cids...)
+	m.pb.queue = m.orderQueue(m.pb.pool)
+	m.pb.radio = make(map[uint64]bool)
+	m.pb.index = 0
+	return m.loadCurrent()
+}
+
+// orderQueue 按播放模式排出播放顺序
+func (m *model) orderQueue(pool []uint64) []uint64 {
+	list := append([]uint64(nil), pool...)
+	switch m.playMode {
+	case PlayModeShuffle:
+		m.pb.picker.Shuffle(list)
+	case PlayModeShuffleBag:
+		if rest := bagRemaining(pool); len(rest) > 0 {
+			return rest
+		}
+		m.pb.picker.Shuffle(list)
+	case PlayModeWeighted:
+		// 加权随机不预先排好，每播完一首再抽下一首
+		if cid, ok := m.pb.picker.Next(pool, m.shuffleStats, time.Now()); ok {
+			return []uint64{cid}
+		}
+	}
+	return list
+}
+
+// shuffleStats 汇总加权随机需要的曲目信息
+func (m *model) shuffleStats(cid uint64) shuffle.Stats {
+	s := shuffle.Stats{Rating: m.itemStars(cid)}
+	if pos, ok := m.positions[cid]; ok && pos.Updated > 0 {
+		s.LastPlayed = time.Unix(pos.Updated, 0)
+	}
+	if st, ok := m.stats[cid]; ok {
+		s.PlayCount = st.Plays
+		if last := time.Unix(st.LastPlayed, 0); st.LastPlayed > 0 && last.After(s.LastPlayed) {
+			s.LastPlayed = last
+		}
+	}
+	return s
+}
+
+// bagRemaining 读取该范围上次没播完的袋子，只保留仍在范围内的 CID
+func bagRemaining(pool []uint64) []uint64 {
+	saved, _ := userdata.LoadShuffleBag(userdata.PoolKey(pool))
+	in := make(map[uint64]bool, len(pool))
+	for _, cid := range pool {
+		in[cid] = true
+	}
+	var rest []uint64
+	for _, cid := range saved {
+		if in[cid] {
+			rest = append(rest, cid)
+		}
+	}
+	return rest
+}
+
+// refillBag 袋子播空后重新装满，避免新一轮的第一首与刚播完的重复
+func (m *model) refillBag() {
+	last, _ := m.pb.current()
+	list := append([]uint64(nil), m.pb.pool...)
+	m.pb.picker.Shuffle(list)
+	if len(list) > 1 && list[0] == last {
+		list[0], list[len(list)-1] = list[len(list)-1], list[0]
+	}
+	m.pb.queue = list
+}
+
+// saveBag 在随机不重复模式下记录尚未开始播放的部分
+func (m *model) saveBag() {
+	if m.playMode != PlayModeShuffleBag || len(m.pb.pool) == 0 {
+		return
+	}
+	rest := m.pb.queue[m.pb.index+1:]
+	if err := userdata.SaveShuffleBag(userdata.PoolKey(m.pb.pool), rest); err != nil {
+		m.pb.status = "❗ " + err.Error()
+	}
+}
+
+func (m *model) loadCurrent() tea.Cmd {
+	cid, ok := m.pb.current()
+	if !ok {
+		m.stopSequence()
+		return nil
+	}
+	lastOfTitle := m.pb.index+1 == len(m.pb.queue) || m.titleOf[m.pb.queue[m.pb.index+1]] != m.titleOf[cid]
+	if !m.sleep.startTrack(lastOfTitle) {
+		m.stopSequence()
+		return nil
+	}
+	m.pb.seq++
+	var duration float64
+	if item, ok := m.itemByCID(cid); ok {
+		duration = float64(item.Duration)
+	}
+	return resolveTrack(m.cfg, m.pb.seq, cid, m.needsTrimDetect(cid), duration)
+}
+
+func (m *model) onTrackReady(msg trackReadyMsg) tea.Cmd {
+	if msg.seq != m.pb.seq {
+		return nil
+	}
+	if msg.err != nil {
+		return m.trackFailed(msg.err)
+	}
+	if msg.trim != nil {
+		m.saveDetectedTrim(msg.cid, *msg.trim)
+	}
+
+	t := player.Track{CID: msg.cid, Name: m.trackName(msg.cid), File: msg.file, Filters: m.trackFilters(msg.cid)}
+	item, ok := m.itemByCID(msg.cid)
+	if ok {
+		t.Duration = time.Duration(item.Duration) * time.Second
+	}
+	if isSegment(msg.cid) {
+		// 分段的起止由 CUE / 时间戳决定，不再裁剪静音
+		t.Base = time.Duration(item.Start * float64(time.Second))
+		if item.End > 0 {
+			t.End = time.Duration((item.End - item.Start) * float64(time.Second))
+		}
+	} else {
+		t.Start, t.End = m.trimFor(msg.cid)
+	}
+
+	if pos := m.positions[msg.cid]; pos.InProgress() && t.Duration >= resumeMinDuration {
+		switch m.state {
+		case StateTUI, StateQueue, StateHistory, StateKeys:
+			m.pb.pending = t
+			m.resumeBack = m.state
+			m.state = StateResumePrompt
+			return nil
+		}
+		// 正在输入、选择或调整分段时不打断，直接从上次的位置继续
+		cmd := m.startTrack(t, time.Duration(pos.Pos*float64(time.Second)))
+		m.pb.status = "已从 " + formatSeconds(pos.Pos) + " 继续"
+		return cmd
+	}
+	return m.startTrack(t, 0)
+}
+
+// answerResume 处理"是否继续"的回答
+func (m *model) answerResume(resume bool) tea.Cmd {
+	m.state = m.resumeBack
+	t := m.pb.pending
+	m.pb.pending = player.Track{}
+	at := time.Duration(0)
+	if resume {
+		at = time.Duration(m.positions[t.CID].Pos * float64(time.Second))
+	}
+	return m.startTrack(t, at)
+}
+
+// trackName 与 play 脚本一致：分P名与标题相同时只显示一个
+func (m *model) trackName(cid uint64) string {
+	key, ok := m.titleOf[cid]
+	if !ok {
+		return fmt.Sprintf("%d", cid)
+	}
+	title := m.groups[key[0]].Titles[key[1]]
+	for _, item := range title.Items {
+		if item.CID == cid && item.Title != title.Name {
+			return title.Name + ":" + item.Title
+		}
+	}
+	return title.Name
+}
+
+func (m *model) itemByCID(cid uint64) (Item, bool) {
+	key, ok := m.titleOf[cid]
+	if !ok {
+		return Item{}, false
+	}
+	for _, item := range m.groups[key[0]].Titles[key[1]].Items {
+		if item.CID == cid {
+			return item, true
+		}
+	}
+	return Item{}, false
+}
+
+func (m *model) startTrack(t player.Track, at time.Duration) tea.Cmd {
+	m.pb.clearLoop()
+	m.pb.fading = false
+	m.pb.bmIdx = 0
+	if err := m.pb.player.Play(t, at); err != nil {
+		return m.trackFailed(err)
+	}
+	m.pb.failed = 0
+	m.recordHistory(userdata.HistoryStart, t, max(at, t.Start))
+	m.pb.picker.Played(t.CID)
+	m.pb.status = ""
+	m.saveQueue()
+	return m.ensurePlaybackTick()
+}
+
+// failureLimit 返回连续失败多少首后停止：一般为队列长度；
+// 加权随机和电台每次失败都会再抽一首追加到队列，队列会越来越长，
+// 前者以播放范围的大小为上限，后者（从整个曲库中抽取）最多再试 maxRadioFailures 首
+func (m *model) failureLimit() int {
+	if cid, ok := m.pb.current(); ok && m.pb.radio[cid] {
+		return maxRadioFailures
+	}
+	if m.playMode == PlayModeWeighted {
+		return len(m.pb.pool)
+	}
+	return len(m.pb.queue)
+}
+
+// trackFailed 跳过无法播放的曲目；连续失败达到 failureLimit 时停止，避免空转
+func (m *model) trackFailed(err error) tea.Cmd {
+	m.pb.failed++
+	if m.pb.failed >= m.failureLimit() {
+		m.stopSequence()
+		m.pb.failed = 0
+		m.pb.status = "❗ " + err.Error()
+		return nil
+	}
+	m.pb.status = "❗ " + err.Error()
+	return m.advance()
+}
+
+func (m *model) ensurePlaybackTick() tea.Cmd {
+	if m.pb.ticking {
+		return nil
+	}
+	m.pb.ticking = true
+	return playbackTick()
+}
+
+// advance 切到队列的下一首，列表循环和随机不重复模式到结尾后从头开始
+func (m *model) advance() tea.Cmd {
+	if m.pb.index+1 >= len(m.pb.queue) && len(m.pb.queue) > 0 {
+		switch m.playMode {
+		case PlayModeWeighted:
+			if cid, ok := m.pb.picker.Next(m.pb.pool, m.shuffleStats, time.Now()); ok {
+				m.pb.queue = append(m.pb.queue, cid)
+			}
+		case PlayModeRepeatAll:
+			m.pb.index = 0
+			return m.loadCurrent()
+		case PlayModeShuffleBag:
+			m.refillBag()
+			m.pb.index = 0
+			return m.loadCurrent()
+		default:
+			if m.pb.radioOn {
+				m.radioExtend()
+			}
+		}
+	}
+	m.pb.index++
+	return m.loadCurrent()
+}
+
+// stopTrack 记录进度并结束当前曲目
+func (m *model) stopTrack() {
+	if t, ok := m.pb.player.Track(); ok {
+		m.savePosition(false)
+		m.recordStop(t, m.pb.player.Position())
+	}
+	m.pb.player.Stop()
+	m.pb.clearLoop()
+}
+
+// stopSequence 结束整个队列
+func (m *model) stopSequence() {
+	m.stopTrack()
+	m.pb.queue = nil
+	m.pb.index = 0
+	m.pb.seq++
+	m.saveQueue()
+}
+
+// togglePause 暂停 / 继续；没有在播放时从队列的当前项开始（如恢复的队列）
+func (m *model) togglePause() tea.Cmd {
+	if _, ok := m.pb.player.Track(); ok {
+		m.pb.player.TogglePause()
+		return nil
+	}
+	if _, ok := m.pb.current(); ok {
+		return m.loadCurrent()
+	}
+	return nil
+}
+
+// quit 保留队列退出，下次启动时恢复
+func (m *model) quit() tea.Cmd {
+	m.shutdown()
+	return tea.Quit
+}
+
+// shutdown 在退出或重启前停止播放并保存队列，不留下孤儿子进程
+func (m *model) shutdown() {
+	m.stopTrack()
+	m.pb.seq++
+	m.saveQueue()
+}
+
+// skipTrack 结束当前曲目，睡眠定时器未到点时播放下一首
+func (m *model) skipTrack() tea.Cmd {
+	if _, ok := m.pb.current(); !ok {
+		return nil
+	}
+	m.stopTrack()
+	if m.sleep.finishTrack() {
+		m.stopSequence()
+		return nil
+	}
+	return m.advance()
+}
+
+// 上一首：已播放超过这个时长时改为从头重播当前曲目
+const restartThreshold = 3 * time.Second
+
+// prevTrack 播放队列中的上一首
+func (m *model) prevTrack() tea.Cmd {
+	if _, ok := m.pb.current(); !ok {
+		return nil
+	}
+	if t, ok := m.pb.player.Track(); ok && (m.pb.index == 0 || m.pb.player.Position()-t.Start > restartThreshold) {
+		m.pb.player.Seek(0)
+		return nil
+	}
+	if m.pb.index == 0 {
+		return nil
+	}
+	m.stopTrack()
+	m.pb.index--
+	return m.loadCurrent()
+}
+
+// stopQueue 结束当前曲目并放弃队列剩余部分
+func (m *model) stopQueue() {
+	if _, ok := m.pb.current(); !ok {
+		return
+	}
+	m.stopSequence()
+	m.pb.status = "已停止播放队列"
+}
+
+func (m *model) onPlaybackTick() tea.Cmd {
+	p := m.pb.player
+	t, ok := p.Track()
+	if !ok {
+		m.pb.ticking = false
+		return nil
+	}
+	m.pb.countListen()
+
+	if p.Ended() {
+		if m.pb.looping() && !m.pb.fading {
+			p.Seek(m.pb.loopA)
+			return playbackTick()
+		}
+		m.savePosition(true)
+		m.recordHistory(userdata.HistoryFinish, t, p.Position())
+		if m.sleep.finishTrack() {
+			p.Stop()
+			m.stopSequence()
+			m.pb.ticking = false
+			return nil
+		}
+		if m.playMode == PlayModeRepeatOne {
+			p.Seek(0)
+			m.recordHistory(userdata.HistoryStart, t, t.Start)
+			return playbackTick()
+		}
+		p.Stop()
+		m.pb.ticking = false
+		return m.advance()
+	}
+
+	pos := p.Position()
+	if m.pb.looping() && !m.pb.fading && !p.Paused() && pos >= m.pb.loopB {
+		p.Seek(m.pb.loopA)
+	}
+
+	if m.sleep.expired() {
+		m.stopSequence()
+		m.sleep.expireIdle()
+		m.pb.status = "💤 睡眠定时器到点，已停止播放"
+		m.pb.ticking = false
+		return nil
+	}
+	end := t.Duration
+	if t.End > 0 {
+		end = t.End
+	}
+	if at, ok := m.sleep.stopAt(end, pos); ok {
+		left := time.Until(at)
+		if !m.pb.fading && !p.Paused() && left <= sleepFade {
+			p.SetFilters(joinFilters(m.trackFilters(t.CID), fadeFilter(left)))
+			m.pb.fading = true
+		}
+	} else if m.pb.fading && !m.sleep.active() {
+		// 定时器被取消，恢复音量
+		p.SetFilters(m.trackFilters(t.CID))
+		m.pb.fading = false
+	}
+	return playbackTick()
+}
+
+// savePosition 为长条目记录播放进度
+func (m *model) savePosition(finished bool) {
+	t, ok := m.pb.player.Track()
+	if !ok || t.Duration < resumeMinDuration {
+		return
+	}
+	pos := m.pb.player.Position()
+	if pos >= t.Duration-5*time.Second {
+		finished = true
+	}
+	p := userdata.Position{
+		Pos:      pos.Seconds(),
+		Duration: t.Duration.Seconds(),
+		Finished: finished,
+		Updated:  time.Now().Unix(),
+	}
+	if finished {
+		p.Pos = 0
+	}
+	if err := userdata.SavePosition(t.CID, p); err != nil {
+		m.pb.status = "❗ " + err.Error()
+		return
+	}
+	m.positions[t.CID] = p
+	m.rebuildAllNodes()
+	m.rebuildVisible()
+}
+
+// ========== A-B 循环与书签 ==========
+func (m *model) setLoopA() {
+	if _, ok := m.pb.player.Track(); !ok {
+		return
+	}
+	m.pb.loopA = m.pb.player.Position()
+	m.pb.loopB = -1
+}
+
+func (m *model) setLoopB() {
+	if _, ok := m.pb.player.Track(); !ok || m.pb.loopA < 0 {
+		return
+	}
+	if pos := m.pb.player.Position(); pos > m.pb.loopA {
+		m.pb.loopB = pos
+	}
+}
+
+func (m *model) saveBookmark(name string) {
+	t, ok := m.pb.player.Track()
+	if !ok || !m.pb.looping() || name == "" {
+		return
+	}
+	bm := userdata.Bookmark{Name: name, A: m.pb.loopA.Seconds(), B: m.pb.loopB.Seconds()}
+	if err := userdata.SaveBookmark(t.CID, bm); err != nil {
+		m.pb.status = "❗ " + err.Error()
+		return
+	}
+	m.pb.status = fmt.Sprintf("已保存书签: %s (%s-%s)", name, formatSeconds(bm.A), formatSeconds(bm.B))
+}
+
+// nextBookmark 依次切换到当前曲目保存过的书签并开始循环
+func (m *model) nextBookmark() {
+	t, ok := m.pb.player.Track()
+	if !ok {
+		return
+	}
+	bookmarks, _ := userdata.LoadBookmarks()
+	list := bookmarks[t.CID]
+	if len(list) == 0 {
+		m.pb.status = "当前曲目没有书签"
+		return
+	}
+	bm := list[m.pb.bmIdx%len(list)]
+	m.pb.bmIdx++
+	m.pb.loopA = time.Duration(bm.A * float64(time.Second))
+	m.pb.loopB = time.Duration(bm.B * float64(time.Second))
+	m.pb.player.Seek(m.pb.loopA)
+	m.pb.status = "书签: " + bm.Name
+}
+
+// ========== 正在播放面板 ==========
+const nowPlayingHeight = 3 + 4 // 状态行 + 下一首 + 提示行 + 刷屏行
+
+// upNextView 显示接下来的几首，📻 标出电台追加的曲目
+func (m model) upNextView() string {
+	const shown = 3
+	var names []string
+	for i := m.pb.index + 1; i < len(m.pb.queue) && len(names) < shown; i++ {
+		name := m.trackName(m.pb.queue[i])
+		if m.pb.radio[m.pb.queue[i]] {
+			name += " 📻"
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		if m.pb.radioOn {
+			return "下一首: 📻 电台续播"
+		}
+		return ""
+	}
+	return "下一首: " + strings.Join(names, " · ")
+}
+
+func (m model) nowPlayingView() string {
+	p := m.pb.player
+	t, ok := p.Track()
+	if !ok {
+		line := "■ 未在播放"
+		if n := len(m.pb.queue); n > 0 {
+			line += fmt.Sprintf("  队列 [%d/%d]（p=继续播放  Tab=查看队列）", m.pb.index+1, n)
+		}
+		if m.pb.status != "" {
+			line += "  " + m.pb.status
+		}
+		return line + strings.Repeat("\n", nowPlayingHeight-1)
+	}
+
+	pos := p.Position()
+	state := "▶"
+	if p.Paused() {
+		state = "⏸"
+	}
+	line := fmt.Sprintf("%s [%d/%d] %s |%s| %s/%s", state, m.pb.index+1, len(m.pb.queue), t.Name,
+		progressBar(pos, t.Duration, m.pb.loopA, m.pb.loopB, 30),
+		formatSeconds(pos.Seconds()), formatSeconds(t.Duration.Seconds()))
+	if m.pb.looping() {
+		line += fmt.Sprintf("  ⟳ %s-%s", formatSeconds(m.pb.loopA.Seconds()), formatSeconds(m.pb.loopB.Seconds()))
+	} else if m.pb.loopA >= 0 {
+		line += "  A=" + formatSeconds(m.pb.loopA.Seconds())
+	}
+
+	lines := []string{line, m.upNextView(), m.pb.status}
+	hex := p.HexLines()
+	for i := 0; i < nowPlayingHeight-3; i++ {
+		if i < len(hex) {
+			lines = append(lines, hex[i])
+		} else {
+			lines = append(lines, "")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// progressBar：= 已播放，[ ] 标出 A / B 点
+func progressBar(pos, dur, a, b time.Duration, width int) string {
+	filled, aCol, bCol := 0, -1, -1
+	if dur > 0 {
+		filled = int(int64(pos) * int64(width) / int64(dur))
+		if a >= 0 {
+			aCol = int(int64(a) * int64(width) / int64(dur))
+		}
+		if b >= 0 {
+			bCol = int(int64(b) * int64(width) / int64(dur))
+		}
+	}
+	var sb strings.Builder
+	for i := 0; i < width; i++ {
+		switch {
+		case i == aCol:
+			sb.WriteByte('[')
+		case i == bCol:
+			sb.WriteByte(']')
+		case i < filled:
+			sb.WriteByte('=')
+		default:
+			sb.WriteByte('-')
+		}
+	}
+	return sb.String()
+}
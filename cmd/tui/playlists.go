@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// ========== 播放列表 ==========
+// + 把选中的节点加入播放列表（或新建），r 重命名，D 删除播放列表 / 从列表中移除条目；
+// 智能播放列表见 smart.go
+
+const newPlaylistChoice = "new"
+
+// playlistAt 返回节点所属的播放列表下标
+func (m *model) playlistAt(node TreeNode) (int, bool) {
+	if m.sectionOf(node) != sectionPlaylists || node.Type == NodeGroup {
+		return 0, false
+	}
+	return node.titleIdx, node.titleIdx < len(m.playlists)
+}
+
+// savePlaylists 保存播放列表并刷新目录树；playlists.json 读取失败时只在本次运行中生效
+func (m *model) savePlaylists() bool {
+	if m.playlistsErr != nil {
+		m.refreshVirtualGroups()
+		m.pb.status = readOnlyStatus(m.playlistsErr, "播放列表")
+		return false
+	}
+	if err := userdata.SavePlaylists(m.playlists); err != nil {
+		m.pb.status = "❗ " + err.Error()
+		return false
+	}
+	m.refreshVirtualGroups()
+	return true
+}
+
+// openAddToPlaylist 选择要加入的播放列表
+func (m *model) openAddToPlaylist() {
+	m.pendingCIDs = m.targetCIDs()
+	if len(m.pendingCIDs) == 0 {
+		return
+	}
+	var choices []choice
+	for i, pl := range m.playlists {
+		if pl.Smart() {
+			continue
+		}
+		choices = append(choices, choice{
+			label: fmt.Sprintf("%s（%d 首）", pl.Name, len(pl.Items)),
+			value: strconv.Itoa(i),
+		})
+	}
+	choices = append(choices, choice{label: "＋ 新建播放列表…", value: newPlaylistChoice})
+	m.openChooser(ChoosePlaylist, fmt.Sprintf("将 %d 首加入播放列表", len(m.pendingCIDs)), choices, "")
+}
+
+func (m *model) choosePlaylist(value string) tea.Cmd {
+	if value == newPlaylistChoice {
+		return m.openPrompt(PromptNewPlaylist, "新播放列表名称: ")
+	}
+	if i, err := strconv.Atoi(value); err == nil && i < len(m.playlists) && !m.playlists[i].Smart() {
+		m.addToPlaylist(i, m.pendingCIDs)
+		m.clearMarks()
+	}
+	m.pendingCIDs = nil
+	return nil
+}
+
+func (m *model) findPlaylist(name string) int {
+	for i, pl := range m.playlists {
+		if pl.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// createPlaylist 新建播放列表并放入 m.pendingCIDs
+func (m *model) createPlaylist(name string) {
+	cids := m.pendingCIDs
+	m.pendingCIDs = nil
+	if name == "" {
+		return
+	}
+	if m.findPlaylist(name) >= 0 {
+		m.pb.status = "❗ 已有同名播放列表: " + name
+		return
+	}
+	m.playlists = append(m.playlists, userdata.Playlist{Name: name})
+	m.addToPlaylist(len(m.playlists)-1, cids)
+	m.clearMarks()
+}
+
+// addToPlaylist 追加 cids，已在列表中的跳过
+func (m *model) addToPlaylist(i int, cids []uint64) {
+	pl := &m.playlists[i]
+	in := make(map[uint64]bool, len(pl.Items))
+	for _, cid := range pl.Items {
+		in[cid] = true
+	}
+	added := 0
+	for _, cid := range cids {
+		if !in[cid] {
+			pl.Items = append(pl.Items, cid)
+			in[cid] = true
+			added++
+		}
+	}
+	if m.savePlaylists() {
+		m.pb.status = fmt.Sprintf("已添加 %d 首到「%s」", added, pl.Name)
+	}
+}
+
+// openRenamePlaylist 重命名光标所在的播放列表
+func (m *model) openRenamePlaylist() tea.Cmd {
+	if len(m.visibleNodes) == 0 {
+		return nil
+	}
+	node := m.visibleNodes[m.cursor]
+	i, ok := m.playlistAt(node)
+	if !ok || node.Type != NodeTitle {
+		m.pb.status = "请先选中一个播放列表"
+		return nil
+	}
+	m.promptIdx = i
+	return m.openPromptWith(PromptRenamePlaylist, "重命名为: ", m.playlists[i].Name)
+}
+
+func (m *model) renamePlaylist(i int, name string) {
+	if name == "" || i >= len(m.playlists) || name == m.playlists[i].Name {
+		return
+	}
+	if m.findPlaylist(name) >= 0 {
+		m.pb.status = "❗ 已有同名播放列表: " + name
+		return
+	}
+	old := m.playlists[i].Name
+	m.playlists[i].Name = name
+	// 保持展开状态
+	if g := m.sectionGroup(sectionPlaylists); g != nil && i < len(g.Titles) {
+		g.Titles[i].Name = playlistTitle(m.playlists[i])
+	}
+	if m.savePlaylists() {
+		m.pb.status = fmt.Sprintf("已将「%s」重命名为「%s」", old, name)
+	}
+}
+
+// deleteFromPlaylists 删除光标所在的播放列表（需确认），或把条目从播放列表中移除
+func (m *model) deleteFromPlaylists() {
+	if len(m.visibleNodes) == 0 {
+		return
+	}
+	node := m.visibleNodes[m.cursor]
+	i, ok := m.playlistAt(node)
+	if !ok {
+		return
+	}
+	if node.Type == NodeTitle {
+		m.openChooser(ChooseDeletePlaylist, fmt.Sprintf("删除播放列表「%s」？", m.playlists[i].Name), []choice{
+			{label: "取消", value: ""},
+			{label: "删除", value: strconv.Itoa(i)},
+		}, "")
+		return
+	}
+
+	pl := &m.playlists[i]
+	if pl.Smart() {
+		m.pb.status = "智能播放列表的曲目由查询决定，按 S 修改查询"
+		return
+	}
+	for j, cid := range pl.Items {
+		if cid == node.CID {
+			pl.Items = append(pl.Items[:j], pl.Items[j+1:]...)
+			break
+		}
+	}
+	if m.savePlaylists() {
+		m.pb.status = "已从「" + pl.Name + "」中移除"
+	}
+}
+
+func (m *model) deletePlaylist(value string) {
+	i, err := strconv.Atoi(value)
+	if err != nil || i >= len(m.playlists) {
+		return
+	}
+	name := m.playlists[i].Name
+	m.playlists = append(m.playlists[:i], m.playlists[i+1:]...)
+	if m.savePlaylists() {
+		m.pb.status = "已删除播放列表「" + name + "」"
+	}
+}
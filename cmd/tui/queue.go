@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// ========== 播放队列面板 ==========
+// Tab 在目录树和队列之间切换；队列的每次变化都写入 queue.json，重启后恢复
+
+// restoreQueue 恢复上次退出时的队列，不自动播放
+func (m *model) restoreQueue() {
+	q, err := userdata.LoadQueue()
+	if err != nil || len(q.Items) == 0 {
+		return
+	}
+	m.pb.queue = q.Items
+	m.pb.pool = q.Pool
+	m.pb.index = min(max(q.Index, 0), len(q.Items)-1)
+	for _, cid := range q.Radio {
+		m.pb.radio[cid] = true
+	}
+}
+
+// saveQueue 记录当前队列（随机不重复模式下同时记录袋子）
+func (m *model) saveQueue() {
+	q := userdata.Queue{Items: m.pb.queue, Index: m.pb.index, Pool: m.pb.pool}
+	for _, cid := range m.pb.queue {
+		if m.pb.radio[cid] {
+			q.Radio = append(q.Radio, cid)
+		}
+	}
+	if err := userdata.SaveQueue(q); err != nil {
+		m.pb.status = "❗ " + err.Error()
+	}
+	if len(m.pb.queue) > 0 {
+		m.saveBag()
+	}
+}
+
+func (m *model) openQueue() {
+	m.queueCursor = m.pb.index
+	if m.queueCursor >= len(m.pb.queue) {
+		m.queueCursor = 0
+	}
+	m.state = StateQueue
+	m.scrollQueue()
+}
+
+func (m *model) queueKey(key string) tea.Cmd {
+	n := len(m.pb.queue)
+	switch key {
+	case "tab", "esc":
+		m.state = StateTUI
+		m.refreshViewport()
+		return nil
+	case "q", "ctrl+c":
+		return m.quit()
+	case "p":
+		return m.togglePause()
+	}
+	if n == 0 {
+		return nil
+	}
+	i := m.queueCursor
+	var cmd tea.Cmd
+	switch key {
+	case "j", "down":
+		m.queueCursor = min(i+1, n-1)
+	case "k", "up":
+		m.queueCursor = max(i-1, 0)
+	case "J":
+		if i+1 < n {
+			m.moveQueueEntry(i, i+1)
+			m.queueCursor++
+		}
+	case "K":
+		if i > 0 {
+			m.moveQueueEntry(i, i-1)
+			m.queueCursor--
+		}
+	case "d", "delete":
+		cmd = m.removeQueueEntry(i)
+		m.queueCursor = min(i, len(m.pb.queue)-1)
+	case "c":
+		m.clearQueue()
+		m.queueCursor = 0
+	case "enter":
+		cmd = m.jumpToQueueEntry(i)
+	}
+	m.scrollQueue()
+	return cmd
+}
+
+// scrollQueue 让光标保持在队列面板的可见范围内（第一行是标题）
+func (m *model) scrollQueue() {
+	height := max(m.viewport.Height-1, 1)
+	if m.queueCursor < m.queueTop {
+		m.queueTop = m.queueCursor
+	} else if m.queueCursor >= m.queueTop+height {
+		m.queueTop = m.queueCursor - height + 1
+	}
+	m.queueTop = max(m.queueTop, 0)
+}
+
+// moveQueueEntry 交换相邻的两项，当前曲目随之移动
+func (m *model) moveQueueEntry(i, j int) {
+	q := m.pb.queue
+	q[i], q[j] = q[j], q[i]
+	switch m.pb.index {
+	case i:
+		m.pb.index = j
+	case j:
+		m.pb.index = i
+	}
+	m.saveQueue()
+}
+
+// removeQueueEntry 移除第 i 项；移除的是正在播放的曲目时接着播放后一项，没有后一项时停止播放但保留队列
+func (m *model) removeQueueEntry(i int) tea.Cmd {
+	cid := m.pb.queue[i]
+	_, playing := m.pb.player.Track()
+	current := i == m.pb.index
+	if current {
+		// 作废还在解析或检测首尾静音的曲目，否则它就绪后仍会播放被移除的这一项
+		m.pb.seq++
+		if playing {
+			m.stopTrack()
+		}
+	}
+	m.pb.queue = append(m.pb.queue[:i], m.pb.queue[i+1:]...)
+	if !m.queued(cid) {
+		delete(m.pb.radio, cid)
+	}
+	if i < m.pb.index {
+		m.pb.index--
+	}
+	if len(m.pb.queue) == 0 {
+		m.stopSequence()
+		return nil
+	}
+	// 删掉的是最后一项时停在新的最后一项上，剩下的队列保留
+	last := m.pb.index >= len(m.pb.queue)
+	if last {
+		m.pb.index = len(m.pb.queue) - 1
+	}
+	m.saveQueue()
+	if current && playing && !last {
+		return m.loadCurrent()
+	}
+	return nil
+}
+
+// enqueue 把 cids 插到当前曲目之后（next 为 true）或追加到队列末尾，不打断正在播放的曲目
+func (m *model) enqueue(cids []uint64, next bool) {
+	if len(cids) == 0 {
+		return
+	}
+	at := len(m.pb.queue)
+	if next && len(m.pb.queue) > 0 {
+		at = m.pb.index + 1
+	}
+	queue := make([]uint64, 0, len(m.pb.queue)+len(cids))
+	queue = append(queue, m.pb.queue[:at]...)
+	queue = append(queue, cids...)
+	m.pb.queue = append(queue, m.pb.queue[at:]...)
+
+	// 加入的曲目同样算进播放范围，随机类模式重新洗牌时不会丢掉它们
+	inPool := make(map[uint64]bool, len(m.pb.pool))
+	for _, cid := range m.pb.pool {
+		inPool[cid] = true
+	}
+	for _, cid := range cids {
+		if !inPool[cid] {
+			m.pb.pool = append(m.pb.pool, cid)
+			inPool[cid] = true
+		}
+	}
+	m.saveQueue()
+
+	if next {
+		m.pb.status = fmt.Sprintf("已插入 %d 首到下一首", len(cids))
+	} else {
+		m.pb.status = fmt.Sprintf("已添加 %d 首到队列末尾", len(cids))
+	}
+	if _, playing := m.pb.player.Track(); !playing {
+		m.pb.status += "（p=开始播放）"
+	}
+}
+
+func (m *model) queued(cid uint64) bool {
+	for _, c := range m.pb.queue {
+		if c == cid {
+			return true
+		}
+	}
+	return false
+}
+
+// jumpToQueueEntry 从第 i 项开始播放
+func (m *model) jumpToQueueEntry(i int) tea.Cmd {
+	m.stopTrack()
+	m.pb.index = i
+	return m.loadCurrent()
+}
+
+// clearQueue 停止播放并清空队列
+func (m *model) clearQueue() {
+	m.stopSequence()
+	m.pb.pool = nil
+	m.pb.radio = make(map[uint64]bool)
+	m.saveQueue()
+	m.pb.status = "已清空播放队列"
+}
+
+func (m model) queueView() string {
+	height := max(m.viewport.Height, 2)
+	var lines []string
+	lines = append(lines, fmt.Sprintf("播放队列（%d 首）", len(m.pb.queue)))
+	_, playing := m.pb.player.Track()
+	for i := m.queueTop; i < len(m.pb.queue) && len(lines) < height; i++ {
+		cid := m.pb.queue[i]
+		cursor := "  "
+		if i == m.queueCursor {
+			cursor = "> "
+		}
+		mark := "  "
+		if i == m.pb.index {
+			mark = "■ "
+			if playing {
+				mark = "▶ "
+			}
+		}
+		line := fmt.Sprintf("%s%s%3d. %s", cursor, mark, i+1, m.trackName(cid))
+		if item, ok := m.itemByCID(cid); ok && item.Duration > 0 {
+			line += "  " + formatSeconds(float64(item.Duration))
+		}
+		if m.pb.radio[cid] {
+			line += " 📻"
+		}
+		lines = append(lines, line)
+	}
+	if len(m.pb.queue) == 0 {
+		lines = append(lines, "  （空）")
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m model) queueHelpView() string {
+	return "\n\nj/k=上下  J/K=下移/上移  d=移除  c=清空  Enter=从这里播放  p=暂停  Tab/Esc=返回目录"
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ayazumi/biliCLI/internal/config"
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// newTestModel 返回只带播放状态的 model，用户数据写到临时目录
+func newTestModel(t *testing.T) model {
+	t.Helper()
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	return model{pb: newPlayback(config.Config{}, 1)}
+}
+
+func TestRemoveQueueEntry(t *testing.T) {
+	tests := []struct {
+		name      string
+		queue     []uint64
+		index     int
+		remove    int
+		wantQueue []uint64
+		wantIndex int
+	}{
+		{"移除前面的项", []uint64{1, 2, 3, 4}, 2, 0, []uint64{2, 3, 4}, 1},
+		{"移除后面的项", []uint64{1, 2, 3, 4}, 2, 3, []uint64{1, 2, 3}, 2},
+		{"移除当前项", []uint64{1, 2, 3, 4}, 2, 2, []uint64{1, 2, 4}, 2},
+		{"移除最后一项且是当前项", []uint64{1, 2, 3, 4}, 3, 3, []uint64{1, 2, 3}, 2},
+		{"移除唯一的一项", []uint64{1}, 0, 0, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t)
+			m.pb.queue = slices.Clone(tt.queue)
+			m.pb.index = tt.index
+			seq := m.pb.seq
+			m.removeQueueEntry(tt.remove)
+			if !slices.Equal(m.pb.queue, tt.wantQueue) || m.pb.index != tt.wantIndex {
+				t.Errorf("队列 %v 位置 %d，期望 %v 位置 %d", m.pb.queue, m.pb.index, tt.wantQueue, tt.wantIndex)
+			}
+			// 移除当前项后，正在解析的曲目不应再播放
+			if tt.remove == tt.index && m.pb.seq == seq {
+				t.Error("移除当前项后 seq 没有变化")
+			}
+		})
+	}
+}
+
+func TestRemoveQueueEntryRadio(t *testing.T) {
+	m := newTestModel(t)
+	m.pb.queue = []uint64{1, 2, 3, 2}
+	m.pb.radio[2] = true
+	m.pb.radio[3] = true
+	m.removeQueueEntry(2)
+	if m.pb.radio[3] {
+		t.Error("移除后仍标记为电台曲目")
+	}
+	m.removeQueueEntry(1)
+	if !m.pb.radio[2] {
+		t.Error("队列中还有同一曲目时不应去掉电台标记")
+	}
+}
+
+func TestQueuePersistence(t *testing.T) {
+	m := newTestModel(t)
+	m.pb.queue = []uint64{1, 2, 3}
+	m.pb.index = 1
+	m.pb.pool = []uint64{1, 2, 3, 4}
+	m.pb.radio[3] = true
+	m.pb.radio[9] = true // 已不在队列中的标记不保存
+	m.saveQueue()
+
+	restored := model{pb: newPlayback(config.Config{}, 1)}
+	restored.restoreQueue()
+	if !slices.Equal(restored.pb.queue, m.pb.queue) || restored.pb.index != 1 || !slices.Equal(restored.pb.pool, m.pb.pool) {
+		t.Errorf("恢复后队列 %v 位置 %d 范围 %v", restored.pb.queue, restored.pb.index, restored.pb.pool)
+	}
+	if !restored.pb.radio[3] || restored.pb.radio[9] {
+		t.Errorf("恢复后电台标记 %v", restored.pb.radio)
+	}
+
+	// 位置超出队列时停在最后一项
+	if err := userdata.SaveQueue(userdata.Queue{Items: []uint64{1, 2}, Index: 5}); err != nil {
+		t.Fatal(err)
+	}
+	restored = model{pb: newPlayback(config.Config{}, 1)}
+	restored.restoreQueue()
+	if restored.pb.index != 1 {
+		t.Errorf("恢复后位置 %d，期望 1", restored.pb.index)
+	}
+}
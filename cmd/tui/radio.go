@@ -0,0 +1,93 @@
+package main
+
+import (
+	"time"
+)
+
+// ========== 电台模式 ==========
+// 队列播完后自动续播：依次尝试同一标题、同一分组、其他分组中时长相近的曲目，避开最近播放过的
+
+// 时长相近：相差不超过较长者的 25%
+const radioLengthTolerance = 0.25
+
+// 电台追加的曲目连续这么多首无法播放时停止，避免缓存目录失效时无限续抽
+const maxRadioFailures = 10
+
+// radioPick 以 last 为参照挑选下一首电台曲目
+func (m *model) radioPick(last uint64) (uint64, bool) {
+	key, ok := m.titleOf[last]
+	if !ok {
+		return 0, false
+	}
+	lastItem, _ := m.itemByCID(last)
+
+	queued := make(map[uint64]bool, len(m.pb.queue))
+	for _, cid := range m.pb.queue {
+		queued[cid] = true
+	}
+	fresh := func(cid uint64) bool {
+		return !queued[cid] && !m.pb.picker.Recent(cid)
+	}
+
+	var sameTitle, sameGroup, similar []uint64
+	for gi, g := range m.groups {
+		if g.section != sectionLibrary {
+			continue
+		}
+		for ti, t := range g.Titles {
+			for _, item := range t.Items {
+				if !fresh(item.CID) {
+					continue
+				}
+				switch {
+				case gi == key[0] && ti == key[1]:
+					sameTitle = append(sameTitle, item.CID)
+				case gi == key[0]:
+					sameGroup = append(sameGroup, item.CID)
+				case similarLength(item.Duration, lastItem.Duration):
+					similar = append(similar, item.CID)
+				}
+			}
+		}
+	}
+
+	for _, tier := range [][]uint64{sameTitle, sameGroup, similar} {
+		if cid, ok := m.pb.picker.Next(tier, m.shuffleStats, time.Now()); ok {
+			return cid, true
+		}
+	}
+	return 0, false
+}
+
+func similarLength(a, b uint32) bool {
+	if a == 0 || b == 0 {
+		return false
+	}
+	long, short := float64(a), float64(b)
+	if short > long {
+		long, short = short, long
+	}
+	return long-short <= long*radioLengthTolerance
+}
+
+// radioExtend 在队列末尾追加一首电台曲目
+func (m *model) radioExtend() bool {
+	last, ok := m.pb.current()
+	if !ok {
+		return false
+	}
+	cid, ok := m.radioPick(last)
+	if !ok {
+		return false
+	}
+	m.pb.queue = append(m.pb.queue, cid)
+	m.pb.radio[cid] = true
+	return true
+}
+
+// removeNext 从队列中移除下一首（例如不想听的电台曲目）
+func (m *model) removeNext() {
+	if i := m.pb.index + 1; i < len(m.pb.queue) {
+		m.removeQueueEntry(i)
+	}
+}
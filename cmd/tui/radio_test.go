@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// radioTestModel 的曲库：分组 0 有两个标题，分组 1 中只有 4 与它们时长相近
+func radioTestModel(t *testing.T) model {
+	t.Helper()
+	m := newTestModel(t)
+	m.sleep = newSleepTimer()
+	m.groups = []GroupNode{
+		{Name: "A", Titles: []TitleNode{
+			{Name: "A1", Items: []Item{{CID: 1, Duration: 200}, {CID: 2, Duration: 200}}},
+			{Name: "A2", Items: []Item{{CID: 3, Duration: 600}}},
+		}},
+		{Name: "B", Titles: []TitleNode{
+			{Name: "B1", Items: []Item{{CID: 4, Duration: 240}, {CID: 5, Duration: 500}}},
+		}},
+		{Name: "播放列表", section: sectionPlaylists, Titles: []TitleNode{
+			{Name: "list", Items: []Item{{CID: 6, Duration: 200}}},
+		}},
+	}
+	m.indexTitles()
+	return m
+}
+
+func TestRadioPick(t *testing.T) {
+	tests := []struct {
+		name   string
+		last   uint64
+		queue  []uint64
+		recent []uint64
+		want   uint64
+		ok     bool
+	}{
+		{"先选同一标题", 1, []uint64{1}, nil, 2, true},
+		{"同一标题没有了选同一分组", 1, []uint64{1, 2}, nil, 3, true},
+		{"再选其他分组中时长相近的", 1, []uint64{1, 2, 3}, nil, 4, true},
+		{"避开最近播放过的", 1, []uint64{1}, []uint64{2}, 3, true},
+		{"没有可选的", 1, []uint64{1, 2, 3, 4}, nil, 0, false},
+		{"不在曲库中的曲目", 6, []uint64{6}, nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := radioTestModel(t)
+			m.cfg.Shuffle.NoRepeatWindow = 5
+			m.pb = newPlayback(m.cfg, 1)
+			m.pb.queue = tt.queue
+			for _, cid := range tt.recent {
+				m.pb.picker.Played(cid)
+			}
+			got, ok := m.radioPick(tt.last)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("radioPick(%d) = %d, %v，期望 %d, %v", tt.last, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSimilarLength(t *testing.T) {
+	tests := []struct {
+		a, b uint32
+		want bool
+	}{
+		{200, 200, true},
+		{200, 250, true},
+		{250, 200, true},
+		{200, 267, false},
+		{0, 0, false},
+		{0, 200, false},
+	}
+	for _, tt := range tests {
+		if got := similarLength(tt.a, tt.b); got != tt.want {
+			t.Errorf("similarLength(%d, %d) = %v，期望 %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFailureLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  PlayMode
+		radio bool
+		want  int
+	}{
+		{"顺序播放为队列长度", PlayModeSequential, false, 3},
+		{"加权随机为播放范围大小", PlayModeWeighted, false, 5},
+		{"电台曲目为固定上限", PlayModeSequential, true, maxRadioFailures},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := radioTestModel(t)
+			m.playMode = tt.mode
+			m.pb.queue = []uint64{1, 2, 3}
+			m.pb.pool = []uint64{1, 2, 3, 4, 5}
+			m.pb.radio[1] = tt.radio
+			if got := m.failureLimit(); got != tt.want {
+				t.Errorf("failureLimit = %d，期望 %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// 连续失败达到上限时停止，之前跳到下一首
+func TestTrackFailedStops(t *testing.T) {
+	m := radioTestModel(t)
+	m.pb.queue = []uint64{1, 2, 3}
+	err := errors.New("未找到音频文件")
+	for i := 1; i < 3; i++ {
+		if m.trackFailed(err) == nil || m.pb.index != i {
+			t.Fatalf("第 %d 次失败后位置 %d，应跳到下一首", i, m.pb.index)
+		}
+	}
+	m.trackFailed(err)
+	if len(m.pb.queue) != 0 || m.pb.failed != 0 {
+		t.Errorf("达到上限后队列 %v，失败计数 %d，应停止播放", m.pb.queue, m.pb.failed)
+	}
+}
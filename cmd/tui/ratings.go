@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// ========== 收藏与评分 ==========
+// f 收藏 / 取消收藏，1-5 评分，0 清除评分；作用于光标所在的条目或曲库中的标题，有标记时作用于全部标记的条目
+
+// ratingGlyphs 是目录树中显示的收藏和评分标记
+func ratingGlyphs(r userdata.Rating) string {
+	var s string
+	if r.Favourite {
+		s += " ♥"
+	}
+	if r.Stars > 0 {
+		s += " " + strings.Repeat("★", r.Stars)
+	}
+	return s
+}
+
+// 收藏分组中存放收藏单曲的标题
+const favouriteSingles = "♪ 单曲"
+
+// rateable 表示标题可以收藏 / 评分：曲库中的标题，以及收藏分组中引用的曲库标题
+func rateable(g GroupNode, title string) bool {
+	switch g.section {
+	case sectionLibrary:
+		return true
+	case sectionFavourites:
+		return title != favouriteSingles
+	}
+	return false
+}
+
+// titleKey 返回标题在评分和标签中的键，收藏分组引用的标题使用它在曲库中的键
+func titleKey(g GroupNode, t TitleNode) string {
+	if t.source != "" {
+		return userdata.TitleKey(t.source, t.Name)
+	}
+	return userdata.TitleKey(g.Name, t.Name)
+}
+
+// nodeTitleKey 返回标题节点的键
+func (m *model) nodeTitleKey(node TreeNode) string {
+	g := m.groups[node.groupIdx]
+	return titleKey(g, g.Titles[node.titleIdx])
+}
+
+// migrateTitleKeys 把旧版只按标题名保存的记录换成 TitleKey，同名的标题各得一份；
+// 曲库中已找不到的保持原样，不会丢失
+func migrateTitleKeys[V any](titles map[string]V, lib []GroupNode) {
+	for name, v := range titles {
+		if strings.Contains(name, "\x00") {
+			continue
+		}
+		found := false
+		for _, g := range lib {
+			for _, t := range g.Titles {
+				if t.Name == name {
+					titles[userdata.TitleKey(g.Name, t.Name)] = v
+					found = true
+				}
+			}
+		}
+		if found {
+			delete(titles, name)
+		}
+	}
+}
+
+// titleRating 返回标题的收藏和评分
+func (m *model) titleRating(g GroupNode, t TitleNode) userdata.Rating {
+	if !rateable(g, t.Name) {
+		return userdata.Rating{}
+	}
+	return m.ratings.Titles[titleKey(g, t)]
+}
+
+// itemStars 返回加权随机使用的评分：条目没有评分时沿用所在标题的评分
+func (m *model) itemStars(cid uint64) int {
+	if r := m.ratings.Items[cid]; r.Stars > 0 {
+		return r.Stars
+	}
+	if key, ok := m.titleOf[cid]; ok {
+		g := m.groups[key[0]]
+		return m.ratings.Titles[titleKey(g, g.Titles[key[1]])].Stars
+	}
+	return 0
+}
+
+// rateTargets 对标记的条目或光标所在节点应用 update，返回提示中使用的对象名
+func (m *model) rateTargets(update func(userdata.Rating) userdata.Rating) (string, bool) {
+	if m.selected.Len() > 0 {
+		cids := m.targetCIDs()
+		for _, cid := range cids {
+			m.ratings.Items[cid] = update(m.ratings.Items[cid])
+		}
+		m.clearMarks()
+		return fmt.Sprintf("%d 首", len(cids)), true
+	}
+	if len(m.visibleNodes) == 0 {
+		return "", false
+	}
+	node := m.visibleNodes[m.cursor]
+	switch {
+	case node.Type == NodeItem:
+		m.ratings.Items[node.CID] = update(m.ratings.Items[node.CID])
+		return node.Name, true
+	case node.Type == NodeTitle && rateable(m.groups[node.groupIdx], node.Name):
+		key := m.nodeTitleKey(node)
+		m.ratings.Titles[key] = update(m.ratings.Titles[key])
+		return node.Name, true
+	}
+	m.pb.status = "只能为条目或曲库中的标题收藏 / 评分"
+	return "", false
+}
+
+// saveRatings 保存收藏和评分并刷新目录树，保存失败时返回 false 并留下提示
+func (m *model) saveRatings() bool {
+	for cid, r := range m.ratings.Items {
+		if r.Empty() {
+			delete(m.ratings.Items, cid)
+		}
+	}
+	for key, r := range m.ratings.Titles {
+		if r.Empty() {
+			delete(m.ratings.Titles, key)
+		}
+	}
+	m.refreshVirtualGroups()
+	if m.ratingsErr != nil {
+		m.pb.status = readOnlyStatus(m.ratingsErr, "收藏和评分")
+		return false
+	}
+	if err := userdata.SaveRatings(m.ratings); err != nil {
+		m.pb.status = "❗ " + err.Error()
+		return false
+	}
+	return true
+}
+
+// toggleFavourite 收藏 / 取消收藏；标记了多首时只要有一首未收藏就全部收藏
+func (m *model) toggleFavourite() {
+	on := true
+	if m.selected.Len() > 0 {
+		on = false
+		for _, cid := range m.targetCIDs() {
+			if !m.ratings.Items[cid].Favourite {
+				on = true
+				break
+			}
+		}
+	} else if len(m.visibleNodes) > 0 {
+		node := m.visibleNodes[m.cursor]
+		switch node.Type {
+		case NodeItem:
+			on = !m.ratings.Items[node.CID].Favourite
+		case NodeTitle:
+			on = !m.ratings.Titles[m.nodeTitleKey(node)].Favourite
+		}
+	}
+	name, ok := m.rateTargets(func(r userdata.Rating) userdata.Rating {
+		r.Favourite = on
+		return r
+	})
+	if !ok {
+		return
+	}
+	if !m.saveRatings() {
+		return
+	}
+	if on {
+		m.pb.status = "♥ 已收藏: " + name
+	} else {
+		m.pb.status = "已取消收藏: " + name
+	}
+}
+
+// setStars 设置评分，0 为清除
+func (m *model) setStars(stars int) {
+	name, ok := m.rateTargets(func(r userdata.Rating) userdata.Rating {
+		r.Stars = stars
+		return r
+	})
+	if !ok {
+		return
+	}
+	if !m.saveRatings() {
+		return
+	}
+	if stars == 0 {
+		m.pb.status = "已清除评分: " + name
+	} else {
+		m.pb.status = fmt.Sprintf("%s %s", strings.Repeat("★", stars), name)
+	}
+}
+
+// favouritesGroup 生成"收藏"分组：收藏的标题各占一项，收藏的单曲归在最前面的"单曲"下
+func (m *model) favouritesGroup(lib []GroupNode, items map[uint64]Item) GroupNode {
+	var singles []uint64
+	var titles []TitleNode
+	for _, g := range lib {
+		for _, t := range g.Titles {
+			if m.ratings.Titles[titleKey(g, t)].Favourite {
+				titles = append(titles, TitleNode{Name: t.Name, Items: t.Items, source: g.Name})
+			}
+			for _, item := range t.Items {
+				if m.ratings.Items[item.CID].Favourite {
+					singles = append(singles, item.CID)
+				}
+			}
+		}
+	}
+	if len(singles) > 0 {
+		titles = append([]TitleNode{{Name: favouriteSingles, Items: referencedItems(singles, items)}}, titles...)
+	}
+	return GroupNode{Name: "❤ 收藏", section: sectionFavourites, Titles: titles}
+}
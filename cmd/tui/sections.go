@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+)
+
+// ========== 虚拟分组 ==========
+// 目录树底部的播放列表等分组，条目引用曲库中的曲目；
+// titleOf 只索引曲库分组，曲目名、所在标题等仍以曲库为准。
+// 虚拟分组放在曲库之后，它们随播放刷新时不会挪动曲库中各节点的位置
+
+type section int
+
+const (
+	sectionLibrary section = iota
+	sectionPlaylists
+	sectionStats
+	sectionFavourites
+)
+
+// libraryItems 以完整曲目名（标题:分P）索引曲库中的条目
+func libraryItems(groups []GroupNode) map[uint64]Item {
+	items := make(map[uint64]Item)
+	for _, g := range groups {
+		for _, t := range g.Titles {
+			for _, item := range t.Items {
+				named := item
+				if item.Title != t.Name {
+					named.Title = t.Name + ":" + item.Title
+				}
+				items[item.CID] = named
+			}
+		}
+	}
+	return items
+}
+
+// referencedItems 把 CID 列表转成条目，曲库中已不存在的也保留，方便从列表中删除
+func referencedItems(cids []uint64, lib map[uint64]Item) []Item {
+	items := make([]Item, 0, len(cids))
+	for _, cid := range cids {
+		item, ok := lib[cid]
+		if !ok {
+			item = Item{CID: cid, Title: fmt.Sprintf("%d（不在曲库中）", cid)}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// virtualSections 是目录树底部虚拟分组的顺序
+var virtualSections = []section{sectionPlaylists, sectionFavourites, sectionStats}
+
+func (m *model) virtualGroup(s section, lib []GroupNode, items map[uint64]Item) GroupNode {
+	switch s {
+	case sectionFavourites:
+		return m.favouritesGroup(lib, items)
+	case sectionStats:
+		return m.statsGroup(lib, items)
+	}
+	playlists := GroupNode{Name: "📋 播放列表", section: sectionPlaylists}
+	for _, pl := range m.playlists {
+		cids := pl.Items
+		if pl.Smart() {
+			cids = m.smartItems(lib, pl)
+		}
+		playlists.Titles = append(playlists.Titles, TitleNode{
+			Name:  playlistTitle(pl),
+			Items: referencedItems(cids, items),
+		})
+	}
+	return playlists
+}
+
+// refreshVirtualGroups 重新生成虚拟分组，保留它们的展开状态
+func (m *model) refreshVirtualGroups() {
+	var lib []GroupNode
+	open := make(map[string]bool)
+	for _, g := range m.groups {
+		if g.section == sectionLibrary {
+			lib = append(lib, g)
+			continue
+		}
+		open[g.Name] = g.Open
+		for _, t := range g.Titles {
+			open[g.Name+"/"+t.Name] = t.Open
+		}
+	}
+
+	// 生成虚拟分组时要按曲库中的位置继承评分和标签，先按新的布局建立 titleOf
+	m.groups = make([]GroupNode, 0, len(lib)+len(virtualSections))
+	m.groups = append(m.groups, lib...)
+	for _, s := range virtualSections {
+		m.groups = append(m.groups, GroupNode{section: s})
+	}
+	m.indexTitles()
+	items := libraryItems(lib)
+	for i, s := range virtualSections {
+		g := m.virtualGroup(s, lib, items)
+		g.Open = open[g.Name]
+		for ti := range g.Titles {
+			g.Titles[ti].Open = open[g.Name+"/"+g.Titles[ti].Name]
+		}
+		m.groups[len(lib)+i] = g
+	}
+	m.rebuildAllNodes()
+	m.rebuildVisible()
+	if m.cursor >= len(m.visibleNodes) {
+		m.cursor = max(len(m.visibleNodes)-1, 0)
+	}
+}
+
+// libraryGroups 返回曲库部分的分组
+func (m *model) libraryGroups() []GroupNode {
+	var lib []GroupNode
+	for _, g := range m.groups {
+		if g.section == sectionLibrary {
+			lib = append(lib, g)
+		}
+	}
+	return lib
+}
+
+// sectionGroup 返回指定类型的虚拟分组
+func (m *model) sectionGroup(s section) *GroupNode {
+	for gi := range m.groups {
+		if m.groups[gi].section == s {
+			return &m.groups[gi]
+		}
+	}
+	return nil
+}
+
+// sectionOf 返回节点所在分组的类型
+func (m *model) sectionOf(node TreeNode) section {
+	return m.groups[node.groupIdx].section
+}
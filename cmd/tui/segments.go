@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// ========== 分段（虚拟曲目） ==========
+// 为一个 CID 附上 CUE 或时间戳列表后，它在目录树中被替换成各个分段，
+// 分段像普通条目一样播放、加入队列、搜索和记录进度。
+// 分段的 ID 与 CID 共用 uint64：低 48 位是所在视频的 CID，高 16 位是分段起点的整秒数 + 1。
+// ID 由起点而不是序号决定，增删其它分段后，进度、评分、标签等记录仍然对得上
+
+const (
+	segmentShift  = 48
+	segmentMaxKey = 1<<(64-segmentShift) - 1
+)
+
+// segmentKey 返回起点为 start 秒的分段在 ID 高位的值
+func segmentKey(start float64) uint64 {
+	key := uint64(start) + 1
+	if key > segmentMaxKey {
+		key = segmentMaxKey
+	}
+	return key
+}
+
+func segmentID(cid, key uint64) uint64 {
+	return cid | key<<segmentShift
+}
+
+// sourceCID 返回 id 所在视频的 CID，普通条目原样返回
+func sourceCID(id uint64) uint64 {
+	return id & (1<<segmentShift - 1)
+}
+
+func isSegment(id uint64) bool {
+	return id>>segmentShift != 0
+}
+
+// expandSegments 把有分段的条目替换成各个分段
+func expandSegments(groups []GroupNode, segments map[uint64][]userdata.Segment) {
+	for gi := range groups {
+		for ti := range groups[gi].Titles {
+			title := &groups[gi].Titles[ti]
+			var items []Item
+			for _, item := range title.Items {
+				segs := segments[item.CID]
+				if len(segs) == 0 {
+					items = append(items, item)
+					continue
+				}
+				var prev uint64
+				for i, seg := range segs {
+					// 起点落在同一秒内的分段顺延一位，保证 ID 不重复
+					key := segmentKey(seg.Start)
+					if key <= prev && prev < segmentMaxKey {
+						key = prev + 1
+					}
+					prev = key
+					end := float64(item.Duration)
+					if i+1 < len(segs) {
+						end = segs[i+1].Start
+					}
+					v := Item{
+						Title: fmt.Sprintf("%02d %s", i+1, seg.Title),
+						CID:   segmentID(item.CID, key),
+						Start: seg.Start,
+					}
+					if end > seg.Start {
+						v.Duration = uint32(end - seg.Start)
+					}
+					if i+1 < len(segs) {
+						v.End = end
+					}
+					items = append(items, v)
+				}
+			}
+			title.Items = items
+		}
+	}
+}
+
+// reloadTree 分段变化后重新读取 tree.json，保留展开状态
+func (m *model) reloadTree() {
+	groups := loadTree()
+	expandSegments(groups, m.segments)
+	var virtual []GroupNode
+	open := make(map[[2]string]bool)
+	for _, g := range m.groups {
+		if g.section != sectionLibrary {
+			virtual = append(virtual, g)
+			continue
+		}
+		open[[2]string{g.Name}] = g.Open
+		for _, t := range g.Titles {
+			open[[2]string{g.Name, t.Name}] = t.Open
+		}
+	}
+	for gi := range groups {
+		g := &groups[gi]
+		g.Open = open[[2]string{g.Name}]
+		for ti := range g.Titles {
+			g.Titles[ti].Open = open[[2]string{g.Name, g.Titles[ti].Name}]
+		}
+	}
+	m.groups = append(groups, virtual...)
+	m.refreshVirtualGroups()
+}
+
+// ========== 解析 CUE / 时间戳列表 ==========
+var (
+	// 没有小时时分钟可以超过两位（演唱会等长视频中的 120:00），前面的 \b 避免从数字中间开始匹配
+	timestampRe = regexp.MustCompile(`\b(?:\d+:)?\d+:\d{2}(?:\.\d+)?\b`)
+	numberingRe = regexp.MustCompile(`^\d+\s*[.)、]\s*`)
+	cueIndexRe  = regexp.MustCompile(`^INDEX\s+01\s+(\d+):(\d{2}):(\d{2})`)
+)
+
+// parseSegments 自动识别 CUE 和视频简介里常见的时间戳列表（每行一个时间戳和歌名）
+func parseSegments(text string) ([]userdata.Segment, error) {
+	var segs []userdata.Segment
+	if strings.Contains(text, "INDEX 01") {
+		segs = parseCue(text)
+	} else {
+		segs = parseTimestamps(text)
+	}
+	if len(segs) == 0 {
+		return nil, fmt.Errorf("没有找到任何时间戳")
+	}
+	sort.SliceStable(segs, func(i, j int) bool { return segs[i].Start < segs[j].Start })
+	// 去掉起点重复的分段
+	kept := segs[:1]
+	for _, s := range segs[1:] {
+		if s.Start > kept[len(kept)-1].Start {
+			kept = append(kept, s)
+		}
+	}
+	for i := range kept {
+		if kept[i].Title == "" {
+			kept[i].Title = fmt.Sprintf("第 %d 段", i+1)
+		}
+	}
+	return kept, nil
+}
+
+// CUE 的 INDEX 为 mm:ss:ff，一秒 75 帧
+func parseCue(text string) []userdata.Segment {
+	var segs []userdata.Segment
+	inTrack := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "TRACK "):
+			segs = append(segs, userdata.Segment{Start: -1})
+			inTrack = true
+		case inTrack && strings.HasPrefix(line, "TITLE "):
+			segs[len(segs)-1].Title = unquoteCue(strings.TrimPrefix(line, "TITLE "))
+		case inTrack:
+			if m := cueIndexRe.FindStringSubmatch(line); m != nil {
+				min, _ := strconv.Atoi(m[1])
+				sec, _ := strconv.Atoi(m[2])
+				frames, _ := strconv.Atoi(m[3])
+				segs[len(segs)-1].Start = float64(min*60+sec) + float64(frames)/75
+			}
+		}
+	}
+	var valid []userdata.Segment
+	for _, s := range segs {
+		if s.Start >= 0 {
+			valid = append(valid, s)
+		}
+	}
+	return valid
+}
+
+func unquoteCue(s string) string {
+	s = strings.TrimSpace(s)
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return strings.Trim(s, `"`)
+}
+
+// parseTimestamps 支持 "00:00 歌名"、"1. 歌名 - 03:45"、"1:02:03 | 歌名" 等写法
+func parseTimestamps(text string) []userdata.Segment {
+	var segs []userdata.Segment
+	for _, line := range strings.Split(text, "\n") {
+		loc := timestampRe.FindStringSubmatchIndex(line)
+		if loc == nil {
+			continue
+		}
+		start, err := parseClock(line[loc[0]:loc[1]])
+		if err != nil {
+			continue
+		}
+		title := strings.TrimSpace(line[:loc[0]] + " " + line[loc[1]:])
+		title = numberingRe.ReplaceAllString(title, "")
+		title = strings.Trim(title, " \t-–—|:：.、()（）[]【】")
+		segs = append(segs, userdata.Segment{Title: title, Start: start})
+	}
+	return segs
+}
+
+// ========== 附加分段 ==========
+
+// readSegmentsFile 读取 CUE / 时间戳列表文件，"-" 为标准输入
+func readSegmentsFile(path string) ([]userdata.Segment, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(bufio.NewReader(os.Stdin))
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", path, err)
+	}
+	return parseSegments(string(data))
+}
+
+// runSegments 实现 mytui segments <CID> <文件|-|--clear>
+func runSegments(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("用法: mytui segments <CID> <CUE 或时间戳列表文件，- 为标准输入 | --clear>")
+	}
+	cid, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的 CID: %s", args[0])
+	}
+	if args[1] == "--clear" {
+		if err := userdata.SaveSegments(cid, nil); err != nil {
+			return err
+		}
+		fmt.Printf("已清除 %d 的分段\n", cid)
+		return nil
+	}
+	segs, err := readSegmentsFile(args[1])
+	if err != nil {
+		return err
+	}
+	if err := userdata.SaveSegments(cid, segs); err != nil {
+		return err
+	}
+	for i, s := range segs {
+		fmt.Printf("%02d  %s  %s\n", i+1, formatSeconds(s.Start), s.Title)
+	}
+	fmt.Printf("已为 %d 保存 %d 个分段\n", cid, len(segs))
+	return nil
+}
+
+// openSegmentsPrompt 为光标所在的条目指定分段文件
+func (m *model) openSegmentsPrompt() tea.Cmd {
+	if m.cursor >= len(m.visibleNodes) || m.visibleNodes[m.cursor].Type != NodeItem {
+		m.pb.status = "请先选中一个条目"
+		return nil
+	}
+	m.promptCID = sourceCID(m.visibleNodes[m.cursor].CID)
+	return m.openPrompt(PromptSegments, "CUE / 时间戳列表文件（留空清除分段）: ")
+}
+
+func (m *model) submitSegments(cid uint64, path string) {
+	var segs []userdata.Segment
+	if path != "" {
+		var err error
+		if segs, err = readSegmentsFile(expandHome(path)); err != nil {
+			m.pb.status = "❗ " + err.Error()
+			return
+		}
+	}
+	m.setSegments(cid, segs)
+}
+
+// setSegments 保存 cid 的分段并刷新目录树，segs 为空时清除
+func (m *model) setSegments(cid uint64, segs []userdata.Segment) {
+	if err := userdata.SaveSegments(cid, segs); err != nil {
+		m.pb.status = "❗ " + err.Error()
+		return
+	}
+	if len(segs) == 0 {
+		delete(m.segments, cid)
+		m.pb.status = "已清除分段"
+	} else {
+		m.segments[cid] = segs
+		m.pb.status = fmt.Sprintf("已保存 %d 个分段", len(segs))
+	}
+	m.reloadTree()
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + "/" + rest
+		}
+	}
+	return path
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+func TestParseTimestamps(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []userdata.Segment
+	}{
+		{"mm:ss", "03:45 歌名", []userdata.Segment{{Title: "歌名", Start: 225}}},
+		{"m:ss", "3:45 歌名", []userdata.Segment{{Title: "歌名", Start: 225}}},
+		{"h:mm:ss", "1:02:03 | 歌名", []userdata.Segment{{Title: "歌名", Start: 3723}}},
+		{"三位数的分钟", "120:00 曲名", []userdata.Segment{{Title: "曲名", Start: 7200}}},
+		{"带小数", "00:01.5 开场", []userdata.Segment{{Title: "开场", Start: 1.5}}},
+		{"编号在前、时间在后", "1. 歌名 - 03:45", []userdata.Segment{{Title: "歌名", Start: 225}}},
+		{"编号带括号", "12) 03:45 歌名", []userdata.Segment{{Title: "歌名", Start: 225}}},
+		{"中文顿号编号", "3、 [04:05] 歌名", []userdata.Segment{{Title: "歌名", Start: 245}}},
+		{"歌名中的数字不受影响", "05:00 1999", []userdata.Segment{{Title: "1999", Start: 300}}},
+		{"没有时间戳", "只是一行介绍", nil},
+		{"秒数超过两位不是时间戳", "3:456 歌名", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTimestamps(tt.line); !slices.Equal(got, tt.want) {
+				t.Errorf("parseTimestamps(%q) = %v，期望 %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCue(t *testing.T) {
+	const cue = `PERFORMER "someone"
+TITLE "Live"
+FILE "live.flac" WAVE
+  TRACK 01 AUDIO
+    TITLE "Opening"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "第二首"
+    INDEX 00 03:58:00
+    INDEX 01 04:00:37
+  TRACK 03 AUDIO
+    TITLE "没有 INDEX 01 的轨"
+  TRACK 04 AUDIO
+    INDEX 01 125:30:00
+`
+	want := []userdata.Segment{
+		{Title: "Opening", Start: 0},
+		{Title: "第二首", Start: 240 + 37.0/75},
+		{Title: "", Start: 125*60 + 30},
+	}
+	if got := parseCue(cue); !slices.Equal(got, want) {
+		t.Errorf("parseCue = %v，期望 %v", got, want)
+	}
+}
+
+func TestParseSegments(t *testing.T) {
+	got, err := parseSegments("10:00 第二首\n00:00 开场\n10:00 重复的起点\n20:00\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []userdata.Segment{
+		{Title: "开场", Start: 0},
+		{Title: "第二首", Start: 600},
+		{Title: "第 3 段", Start: 1200},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseSegments = %v，期望 %v", got, want)
+	}
+	if _, err := parseSegments("没有时间戳"); err == nil {
+		t.Error("没有时间戳时应返回错误")
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"github.com/charmbracelet/lipgloss"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ========== 多选 ==========
+// 空格标记光标所在的节点（分组 / 标题标记其下全部条目），v 进入区间选择，Esc 取消；
+// 有标记时 Enter / i / a / + / E 作用于全部标记的曲目，顺序与目录树一致
+
+// 与 internal/ui 的选中样式相同
+var markedStyle = lipgloss.NewStyle().
+	Background(lipgloss.Color("237")).
+	Foreground(lipgloss.Color("229"))
+
+// toggleMark 标记 / 取消标记光标所在的节点，然后移到下一行
+func (m *model) toggleMark() {
+	if len(m.visibleNodes) == 0 {
+		return
+	}
+	node := m.visibleNodes[m.cursor]
+	on := !m.nodeMarked(node)
+	for _, cid := range node.CIDs() {
+		m.selected.Set(cid, on)
+	}
+	if m.cursor < len(m.visibleNodes)-1 {
+		m.cursor++
+	}
+	m.refreshViewport()
+}
+
+// nodeMarked 表示节点下的条目已全部标记
+func (m *model) nodeMarked(node TreeNode) bool {
+	cids := node.CIDs()
+	for _, cid := range cids {
+		if !m.selected.Has(cid) {
+			return false
+		}
+	}
+	return len(cids) > 0
+}
+
+// toggleVisual 进入区间选择；再按一次把区间内的节点全部标记
+func (m *model) toggleVisual() {
+	if !m.visual {
+		m.visual = true
+		m.visualAnchor = m.cursor
+		m.refreshViewport()
+		return
+	}
+	lo, hi := m.visualRange()
+	for i := lo; i <= hi && i < len(m.visibleNodes); i++ {
+		for _, cid := range m.visibleNodes[i].CIDs() {
+			m.selected.Set(cid, true)
+		}
+	}
+	m.visual = false
+	m.refreshViewport()
+}
+
+func (m *model) visualRange() (lo, hi int) {
+	lo, hi = m.visualAnchor, m.cursor
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return lo, hi
+}
+
+// cancelMarks 先退出区间选择，再按一次清除全部标记
+func (m *model) cancelMarks() {
+	if m.visual {
+		m.visual = false
+	} else {
+		m.selected.Clear()
+	}
+	m.refreshViewport()
+}
+
+func (m *model) clearMarks() {
+	m.visual = false
+	m.selected.Clear()
+	m.refreshViewport()
+}
+
+// treeOrder 返回目录树中全部曲目的先后顺序：曲库在前，只出现在播放列表中的在后
+func (m *model) treeOrder() []uint64 {
+	var order []uint64
+	for _, g := range append(m.libraryGroups(), m.groups...) {
+		for _, t := range g.Titles {
+			for _, item := range t.Items {
+				order = append(order, item.CID)
+			}
+		}
+	}
+	return order
+}
+
+// targetCIDs 返回操作对象：有标记时为全部标记的曲目，否则为光标所在的节点
+func (m *model) targetCIDs() []uint64 {
+	if m.selected.Len() > 0 {
+		return m.selected.InOrder(m.treeOrder())
+	}
+	if len(m.visibleNodes) == 0 {
+		return nil
+	}
+	return m.visibleNodes[m.cursor].CIDs()
+}
+
+// renderRow 高亮已标记和区间选择中的行
+func (m *model) renderRow(i int, line string) string {
+	highlight := m.nodeMarked(m.visibleNodes[i])
+	if m.visual {
+		lo, hi := m.visualRange()
+		highlight = highlight || (i >= lo && i <= hi)
+	}
+	if highlight {
+		return markedStyle.Render(line)
+	}
+	return line
+}
+
+// 播放 / 加入队列：作用于标记的曲目时用完即清除标记
+func (m *model) playTargets() tea.Cmd {
+	cids := m.targetCIDs()
+	if len(cids) == 0 {
+		return nil
+	}
+	m.clearMarks()
+	return m.playSequence(cids)
+}
+
+func (m *model) enqueueTargets(next bool) {
+	cids := m.targetCIDs()
+	if len(cids) == 0 {
+		return
+	}
+	m.clearMarks()
+	m.enqueue(cids, next)
+}
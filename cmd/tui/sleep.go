@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ========== 睡眠定时器 ==========
+type SleepMode int
+
+const (
+	SleepOff        SleepMode = iota
+	SleepAfter                // 固定时长后停止
+	SleepEndOfTrack           // 当前曲目结束后停止
+	SleepEndOfTitle           // 当前标题的最后一首结束后停止
+)
+
+// 到点前淡出的时长
+const sleepFade = 10 * time.Second
+
+// z 键依次切换的预设
+var sleepDurations = []time.Duration{15 * time.Minute, 30 * time.Minute, 60 * time.Minute, 90 * time.Minute}
+
+type sleepTickMsg struct{}
+
+type sleepTimer struct {
+	mode        SleepMode
+	preset      int // 当前 SleepAfter 预设在 sleepDurations 中的下标
+	deadline    time.Time
+	lastOfTitle bool // 正在播放的曲目是否是所在标题的最后一首
+}
+
+func newSleepTimer() *sleepTimer {
+	return &sleepTimer{}
+}
+
+// cycle 按 关闭 → 15/30/60/90 分钟 → 本曲结束 → 本标题结束 → 关闭 的顺序切换
+func (s *sleepTimer) cycle() {
+	switch s.mode {
+	case SleepOff:
+		s.mode = SleepAfter
+		s.preset = 0
+	case SleepAfter:
+		if s.preset+1 < len(sleepDurations) {
+			s.preset++
+		} else {
+			s.mode = SleepEndOfTrack
+		}
+	case SleepEndOfTrack:
+		s.mode = SleepEndOfTitle
+	default:
+		s.mode = SleepOff
+	}
+	if s.mode == SleepAfter {
+		s.deadline = time.Now().Add(sleepDurations[s.preset])
+	}
+}
+
+func (s *sleepTimer) active() bool {
+	return s.mode != SleepOff
+}
+
+// startTrack 在每首曲目开始前调用，返回 false 表示定时器已到点、应停止队列
+func (s *sleepTimer) startTrack(lastOfTitle bool) bool {
+	if s.mode == SleepAfter && !time.Now().Before(s.deadline) {
+		s.mode = SleepOff
+		return false
+	}
+	s.lastOfTitle = lastOfTitle
+	return true
+}
+
+// finishTrack 在每首曲目结束后调用，返回 true 表示队列应就此停止
+func (s *sleepTimer) finishTrack() bool {
+	stop := false
+	switch s.mode {
+	case SleepAfter:
+		stop = !time.Now().Before(s.deadline)
+	case SleepEndOfTrack:
+		stop = true
+	case SleepEndOfTitle:
+		stop = s.lastOfTitle
+	}
+	if stop {
+		s.mode = SleepOff
+	}
+	return stop
+}
+
+// stopAt 返回当前曲目应当淡出结束的时刻；duration 未知时"本曲 / 本标题结束"无法淡出
+func (s *sleepTimer) stopAt(duration, pos time.Duration) (time.Time, bool) {
+	switch s.mode {
+	case SleepAfter:
+		return s.deadline, true
+	case SleepEndOfTrack:
+		if duration > 0 {
+			return time.Now().Add(duration - pos), true
+		}
+	case SleepEndOfTitle:
+		if s.lastOfTitle && duration > 0 {
+			return time.Now().Add(duration - pos), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// expired 表示固定时长定时器已到点，播放应立即停止
+func (s *sleepTimer) expired() bool {
+	return s.mode == SleepAfter && !time.Now().Before(s.deadline)
+}
+
+// expireIdle 在空闲时把已过期的固定时长定时器关掉
+func (s *sleepTimer) expireIdle() {
+	if s.expired() {
+		s.mode = SleepOff
+	}
+}
+
+func (s *sleepTimer) String() string {
+	switch s.mode {
+	case SleepAfter:
+		left := time.Until(s.deadline)
+		if left < 0 {
+			left = 0
+		}
+		return "💤 " + formatSeconds(left.Seconds())
+	case SleepEndOfTrack:
+		return "💤 本曲结束"
+	case SleepEndOfTitle:
+		return "💤 本标题结束"
+	default:
+		return ""
+	}
+}
+
+// fadeFilter 从当前位置起用 d 淡出到静音
+func fadeFilter(d time.Duration) string {
+	if d < time.Second {
+		d = time.Second
+	}
+	return fmt.Sprintf("asetpts=PTS-STARTPTS,afade=t=out:st=0:d=%.3f", d.Seconds())
+}
+
+func sleepTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return sleepTickMsg{} })
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ayazumi/biliCLI/internal/query"
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// ========== 智能播放列表 ==========
+// 由查询定义的播放列表，与普通播放列表放在一起，每次打开或播放时按当前的统计、评分和标签重新计算；
+// S 新建，光标在智能播放列表上时 S 编辑它的查询，输入时实时显示匹配的曲目数
+
+// 目录树中智能播放列表名称前的标记
+const smartMark = "🔎 "
+
+// playlistTitle 返回播放列表在目录树中显示的名称
+func playlistTitle(pl userdata.Playlist) string {
+	if pl.Smart() {
+		return smartMark + pl.Name
+	}
+	return pl.Name
+}
+
+// queryFields 汇总一首曲目可供查询的信息
+func (m *model) queryFields(cid uint64, item Item) query.Fields {
+	f := query.Fields{
+		Name:     item.Title,
+		Duration: float64(item.Duration),
+		Rating:   m.itemStars(cid),
+		Tags:     m.itemTagSet(cid),
+	}
+	if key, ok := m.titleOf[cid]; ok {
+		g := m.groups[key[0]]
+		f.Group = g.Name
+		f.Title = g.Titles[key[1]].Name
+		f.Favourite = m.ratings.Titles[titleKey(g, g.Titles[key[1]])].Favourite
+	}
+	f.Favourite = f.Favourite || m.ratings.Items[cid].Favourite
+	if s, ok := m.stats[cid]; ok {
+		f.Plays, f.Skips = s.Plays, s.Skips
+		if s.LastPlayed > 0 {
+			f.LastPlayed = time.Unix(s.LastPlayed, 0)
+		}
+	}
+	return f
+}
+
+// evalQuery 按目录树顺序返回曲库中满足查询的曲目
+func (m *model) evalQuery(lib []GroupNode, q query.Query) []uint64 {
+	now := time.Now()
+	var cids []uint64
+	for _, g := range lib {
+		for _, t := range g.Titles {
+			for _, item := range t.Items {
+				if q.Match(m.queryFields(item.CID, item), now) {
+					cids = append(cids, item.CID)
+				}
+			}
+		}
+	}
+	return cids
+}
+
+// smartItems 计算智能播放列表的曲目，查询有误时为空
+func (m *model) smartItems(lib []GroupNode, pl userdata.Playlist) []uint64 {
+	q, err := query.Parse(pl.Query)
+	if err != nil {
+		return nil
+	}
+	return m.evalQuery(lib, q)
+}
+
+// smartAt 返回光标所在的智能播放列表下标
+func (m *model) smartAt() (int, bool) {
+	if len(m.visibleNodes) == 0 {
+		return 0, false
+	}
+	node := m.visibleNodes[m.cursor]
+	i, ok := m.playlistAt(node)
+	if !ok || node.Type != NodeTitle || !m.playlists[i].Smart() {
+		return 0, false
+	}
+	return i, true
+}
+
+// refreshSmart 光标在播放列表分组中（分组、列表或其中的条目）时重新计算智能播放列表，
+// 在展开、播放和加入队列前调用；重新计算后光标仍停在原来的节点上，条目已不在列表中时停在所在的列表上
+func (m *model) refreshSmart() {
+	if len(m.visibleNodes) == 0 {
+		return
+	}
+	node := m.visibleNodes[m.cursor]
+	if m.sectionOf(node) != sectionPlaylists || !m.hasSmart() {
+		return
+	}
+	m.refreshVirtualGroups()
+	fallback := -1
+	for i, vis := range m.visibleNodes {
+		if vis.groupIdx != node.groupIdx || (vis.Type != NodeGroup && vis.titleIdx != node.titleIdx) {
+			continue
+		}
+		if vis.Type == node.Type && vis.CID == node.CID {
+			m.cursor = i
+			m.refreshViewport()
+			return
+		}
+		if vis.Type == NodeTitle {
+			fallback = i
+		}
+	}
+	if fallback >= 0 {
+		m.cursor = fallback
+		m.refreshViewport()
+	}
+}
+
+func (m *model) hasSmart() bool {
+	for _, pl := range m.playlists {
+		if pl.Smart() {
+			return true
+		}
+	}
+	return false
+}
+
+// openSmartPlaylist 编辑光标所在的智能播放列表，否则新建
+func (m *model) openSmartPlaylist() tea.Cmd {
+	if i, ok := m.smartAt(); ok {
+		m.promptIdx = i
+		return m.openQueryPrompt(m.playlists[i].Query)
+	}
+	return m.openPrompt(PromptSmartName, "智能播放列表名称: ")
+}
+
+func (m *model) submitSmartName(name string) tea.Cmd {
+	if name == "" {
+		return nil
+	}
+	if m.findPlaylist(name) >= 0 {
+		m.pb.status = "❗ 已有同名播放列表: " + name
+		return nil
+	}
+	m.promptIdx = -1
+	m.promptName = name
+	return m.openQueryPrompt("")
+}
+
+func (m *model) openQueryPrompt(value string) tea.Cmd {
+	cmd := m.openPromptWith(PromptSmartQuery, "查询: ", value)
+	m.updateQueryHint()
+	return cmd
+}
+
+// updateQueryHint 在查询输入框下显示匹配的曲目数或错误
+func (m *model) updateQueryHint() {
+	value := m.promptInput.Value()
+	q, err := query.Parse(value)
+	switch {
+	case err != nil:
+		m.promptHint = "❗ " + err.Error()
+	case value == "":
+		m.promptHint = "例如 duration>180 group:\"东方\" rating>=4 -played:7d"
+	default:
+		m.promptHint = fmt.Sprintf("匹配 %d 首", len(m.evalQuery(m.libraryGroups(), q)))
+	}
+}
+
+// submitSmartQuery 保存查询：promptIdx 为 -1 时新建名为 promptName 的智能播放列表
+func (m *model) submitSmartQuery(value string) {
+	if value == "" {
+		m.pb.status = "❗ 查询不能为空"
+		return
+	}
+	if _, err := query.Parse(value); err != nil {
+		m.pb.status = "❗ " + err.Error()
+		return
+	}
+	if m.promptIdx < 0 {
+		m.playlists = append(m.playlists, userdata.Playlist{Name: m.promptName, Query: value})
+		m.promptIdx = len(m.playlists) - 1
+	} else if m.promptIdx < len(m.playlists) {
+		m.playlists[m.promptIdx].Query = value
+	} else {
+		return
+	}
+	if m.savePlaylists() {
+		pl := m.playlists[m.promptIdx]
+		n := len(m.sectionGroup(sectionPlaylists).Titles[m.promptIdx].Items)
+		m.pb.status = fmt.Sprintf("智能播放列表「%s」: %d 首", pl.Name, n)
+	}
+}
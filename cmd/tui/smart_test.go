@@ -0,0 +1,60 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// 子命令中按 loadQueryData 读到的用户数据计算智能播放列表，评分和标签从标题、分组继承
+func TestSmartItemsFromUserData(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	m := model{groups: []GroupNode{
+		{Name: "东方 Project", Titles: []TitleNode{
+			{Name: "Bad Apple!!", Items: []Item{{Title: "P1", CID: 1, Duration: 300}, {Title: "P2", CID: 2, Duration: 60}}},
+			{Name: "Night of Nights", Items: []Item{{Title: "P1", CID: 3, Duration: 240}}},
+		}},
+		{Name: "其他", Titles: []TitleNode{
+			{Name: "Bad Apple!!", Items: []Item{{Title: "P1", CID: 4, Duration: 300}}},
+		}},
+	}}
+	m.indexTitles()
+
+	// 旧版按标题名保存的评分同时作用于两个同名标题
+	if err := userdata.SaveRatings(userdata.Ratings{
+		Items:  map[uint64]userdata.Rating{3: {Stars: 5}},
+		Titles: map[string]userdata.Rating{"Bad Apple!!": {Stars: 4}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := userdata.SaveTags(userdata.Tags{Groups: map[string][]string{"东方 Project": {"touhou"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := userdata.SaveStats(1, userdata.Stats{Plays: 1, LastPlayed: time.Now().Unix()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.loadQueryData(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []uint64
+	}{
+		{"rating>=4", []uint64{1, 2, 3, 4}},
+		{"rating>=4 tag:touhou", []uint64{1, 2, 3}},
+		{"rating>=4 tag:touhou duration>180 -played:7d", []uint64{3}},
+		{`group:"其他"`, []uint64{4}},
+		{"rating>=", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := m.smartItems(m.groups, userdata.Playlist{Name: "smart", Query: tt.query})
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("smartItems(%q) = %v，期望 %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ayazumi/biliCLI/internal/config"
+	"github.com/ayazumi/biliCLI/internal/library"
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// ========== 按静音自动分段 ==========
+// 以足够长的静音为界给出分段建议，在对话框中调整、命名后保存为分段（见 segments.go）
+
+// 对话框中 , / . 每次移动边界的秒数
+const splitNudge = 1.0
+
+// 静音结束处与视频结尾相差不超过这个值就算持续到结尾
+const splitEndSlack = 0.5
+
+type splitSuggestMsg struct {
+	cid  uint64
+	segs []userdata.Segment
+	err  error
+}
+
+type splitDialog struct {
+	cid    uint64
+	segs   []userdata.Segment
+	cursor int
+}
+
+// suggestSplit 在每段位于中间的静音的中点处分段，开头和结尾的静音跳过。
+// duration 为视频时长（秒），未知时传 0
+func suggestSplit(silences []library.Silence, duration float64) []userdata.Segment {
+	segs := []userdata.Segment{{Start: 0}}
+	for _, s := range silences {
+		switch {
+		case s.Start <= 0.1 && s.End > 0:
+			segs[0].Start = s.End
+		case s.End < 0 || (duration > 0 && s.End >= duration-splitEndSlack):
+			// 一直持续到结尾的静音不是边界
+		default:
+			segs = append(segs, userdata.Segment{Start: (s.Start + s.End) / 2})
+		}
+	}
+	for i := range segs {
+		segs[i].Title = fmt.Sprintf("第 %d 段", i+1)
+	}
+	return segs
+}
+
+// sourceDuration 返回 cid 所在视频的时长（秒），已分段的视频取最后一段的终点
+func sourceDuration(groups []GroupNode, cid uint64) float64 {
+	var duration float64
+	for _, g := range groups {
+		for _, t := range g.Titles {
+			for _, item := range t.Items {
+				if sourceCID(item.CID) == cid {
+					duration = math.Max(duration, item.Start+float64(item.Duration))
+				}
+			}
+		}
+	}
+	return duration
+}
+
+func detectSplit(cfg config.Config, cid uint64, duration, minGap float64) ([]userdata.Segment, error) {
+	file, err := library.FindAudio(cfg.Root, cid)
+	if err != nil {
+		return nil, err
+	}
+	silences, err := library.DetectSilence(file, cfg.Split.NoiseDB, minGap)
+	if err != nil {
+		return nil, err
+	}
+	return suggestSplit(silences, duration), nil
+}
+
+// runSplitSuggest 实现 mytui split-suggest [-gap 秒] <CID>，输出可直接交给 segments 的时间戳列表
+func runSplitSuggest(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("split-suggest", flag.ExitOnError)
+	gap := fs.Float64("gap", cfg.Split.MinGap, "至少持续这么久的静音才作为边界（秒）")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("用法: mytui split-suggest [-gap 秒] <CID>")
+	}
+	var cid uint64
+	if _, err := fmt.Sscan(fs.Arg(0), &cid); err != nil {
+		return fmt.Errorf("无效的 CID: %s", fs.Arg(0))
+	}
+	segs, err := detectSplit(cfg, cid, sourceDuration(loadTree(), cid), *gap)
+	if err != nil {
+		return err
+	}
+	for _, s := range segs {
+		fmt.Printf("%s %s\n", formatSeconds(s.Start), s.Title)
+	}
+	return nil
+}
+
+// startSplitSuggest 在后台检测光标所在条目的静音
+func (m *model) startSplitSuggest() tea.Cmd {
+	if m.cursor >= len(m.visibleNodes) || m.visibleNodes[m.cursor].Type != NodeItem {
+		m.pb.status = "请先选中一个条目"
+		return nil
+	}
+	cid := sourceCID(m.visibleNodes[m.cursor].CID)
+	cfg := m.cfg
+	duration := sourceDuration(m.groups, cid)
+	m.pb.status = "正在检测静音…"
+	return func() tea.Msg {
+		segs, err := detectSplit(cfg, cid, duration, cfg.Split.MinGap)
+		return splitSuggestMsg{cid: cid, segs: segs, err: err}
+	}
+}
+
+func (m *model) onSplitSuggest(msg splitSuggestMsg) {
+	if msg.err != nil {
+		m.pb.status = "❗ " + msg.err.Error()
+		return
+	}
+	m.pb.status = ""
+	if len(msg.segs) < 2 {
+		m.pb.status = "没有找到足够长的静音，可调小 split.min_gap"
+		return
+	}
+	// 正在输入或浏览其他面板时不打断，回到目录树后再打开对话框（见 openPendingSplit）
+	if m.state != StateTUI {
+		m.pendingSplit = &splitDialog{cid: msg.cid, segs: msg.segs}
+		m.pb.status = "静音检测已完成，回到目录树后显示分段建议"
+		return
+	}
+	m.split = splitDialog{cid: msg.cid, segs: msg.segs}
+	m.state = StateSplit
+}
+
+// openPendingSplit 在回到目录树时打开检测期间暂存的分段建议，每次 Update 之后调用
+func (m *model) openPendingSplit() {
+	if m.pendingSplit == nil || m.state != StateTUI {
+		return
+	}
+	m.split = *m.pendingSplit
+	m.pendingSplit = nil
+	m.state = StateSplit
+	m.pb.status = ""
+}
+
+func (m *model) splitKey(key string) tea.Cmd {
+	d := &m.split
+	switch key {
+	case "j", "down":
+		if d.cursor < len(d.segs)-1 {
+			d.cursor++
+		}
+	case "k", "up":
+		if d.cursor > 0 {
+			d.cursor--
+		}
+	case ",", ".":
+		d.nudge(key == ".")
+	case "d":
+		// 删除边界：并入上一段
+		if d.cursor > 0 {
+			d.segs = append(d.segs[:d.cursor], d.segs[d.cursor+1:]...)
+			d.cursor--
+		}
+	case "enter":
+		return m.openPromptWith(PromptSplitTitle, "名称: ", d.segs[d.cursor].Title)
+	case "t":
+		return m.openPromptWith(PromptSplitTime, "起点: ", formatSeconds(d.segs[d.cursor].Start))
+	case "w":
+		m.state = StateTUI
+		m.setSegments(d.cid, d.segs)
+	case "esc":
+		m.state = StateTUI
+	}
+	return nil
+}
+
+// nudge 把当前边界前后移动，不越过相邻的边界
+func (d *splitDialog) nudge(later bool) {
+	start := d.segs[d.cursor].Start
+	if later {
+		start += splitNudge
+	} else {
+		start -= splitNudge
+	}
+	if start < 0 {
+		start = 0
+	}
+	if d.cursor > 0 && start <= d.segs[d.cursor-1].Start {
+		return
+	}
+	if d.cursor+1 < len(d.segs) && start >= d.segs[d.cursor+1].Start {
+		return
+	}
+	d.segs[d.cursor].Start = start
+}
+
+func (m *model) submitSplitTitle(value string) {
+	if value != "" {
+		m.split.segs[m.split.cursor].Title = value
+	}
+}
+
+func (m *model) submitSplitTime(value string) {
+	d := &m.split
+	start, err := parseClock(value)
+	if err != nil {
+		m.pb.status = "❗ " + err.Error()
+		return
+	}
+	if (d.cursor > 0 && start <= d.segs[d.cursor-1].Start) ||
+		(d.cursor+1 < len(d.segs) && start >= d.segs[d.cursor+1].Start) {
+		m.pb.status = "❗ 起点必须在前后两段之间"
+		return
+	}
+	d.segs[d.cursor].Start = start
+}
+
+func (m model) splitView() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%s 的分段建议（%d 段）\n\n", m.trackName(m.split.cid), len(m.split.segs))
+	for i, s := range m.split.segs {
+		cursor := "  "
+		if i == m.split.cursor {
+			cursor = "> "
+		}
+		length := ""
+		if i+1 < len(m.split.segs) {
+			length = "  (" + formatSeconds(m.split.segs[i+1].Start-s.Start) + ")"
+		}
+		fmt.Fprintf(&b, "%s%02d  %s  %s%s\n", cursor, i+1, formatSeconds(s.Start), s.Title, length)
+	}
+	b.WriteString("\n（j/k 选择  ,/. 边界前后移 1 秒  t=输入起点  Enter=命名  d=删除边界  w=保存  Esc=放弃）")
+	if m.pb.status != "" {
+		b.WriteString("\n" + m.pb.status)
+	}
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ayazumi/biliCLI/internal/player"
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// ========== 收听统计 ==========
+// 每首曲目的播放次数、跳过次数、累计收听时长和上次播放时间，随收听记录一起更新（stats.json）；
+// "最常播放"等虚拟分组直接由统计算出，不必扫描收听记录
+
+// updateStats 按一条收听记录更新统计。开始播放只更新内存，
+// 听完或跳过时才连同累计的收听时长一起保存并刷新目录树，每次收听只写一次文件
+func (m *model) updateStats(event string, t player.Track) {
+	s := m.stats[t.CID]
+	switch event {
+	case userdata.HistoryStart:
+		s.LastPlayed = time.Now().Unix()
+		m.stats[t.CID] = s
+		m.pb.startListen()
+		return
+	case userdata.HistoryFinish:
+		s.Plays++
+	case userdata.HistorySkip:
+		s.Skips++
+	}
+	m.pb.countListen()
+	s.Listened += m.pb.listened.Seconds()
+	m.pb.listened = 0
+	m.stats[t.CID] = s
+	if err := userdata.SaveStats(t.CID, s); err != nil {
+		m.pb.status = "❗ " + err.Error()
+	}
+	m.refreshVirtualGroups()
+}
+
+// shownPlays 返回目录树中显示的播放次数，关闭显示时为 0
+func (m *model) shownPlays(cid uint64) int {
+	if !m.showCounts {
+		return 0
+	}
+	return m.stats[cid].Plays
+}
+
+// toggleCounts 切换目录树中是否显示播放次数
+func (m *model) toggleCounts() {
+	m.showCounts = !m.showCounts
+	m.rebuildAllNodes()
+	m.rebuildVisible()
+	if m.showCounts {
+		m.pb.status = "显示播放次数"
+	} else {
+		m.pb.status = "隐藏播放次数"
+	}
+}
+
+// statsGroup 生成"统计"分组：最常播放、最近播放、从未播放
+func (m *model) statsGroup(lib []GroupNode, items map[uint64]Item) GroupNode {
+	var played, never []uint64
+	for _, g := range lib {
+		for _, t := range g.Titles {
+			for _, item := range t.Items {
+				if m.stats[item.CID].LastPlayed > 0 {
+					played = append(played, item.CID)
+				} else {
+					never = append(never, item.CID)
+				}
+			}
+		}
+	}
+	limit := max(m.cfg.Stats.Limit, 1)
+
+	var most []uint64
+	for _, cid := range played {
+		if m.stats[cid].Plays > 0 {
+			most = append(most, cid)
+		}
+	}
+	sort.SliceStable(most, func(i, j int) bool {
+		a, b := m.stats[most[i]], m.stats[most[j]]
+		if a.Plays != b.Plays {
+			return a.Plays > b.Plays
+		}
+		return a.Listened > b.Listened
+	})
+	recent := append([]uint64(nil), played...)
+	sort.SliceStable(recent, func(i, j int) bool {
+		return m.stats[recent[i]].LastPlayed > m.stats[recent[j]].LastPlayed
+	})
+
+	return GroupNode{
+		Name:    "📊 统计",
+		section: sectionStats,
+		Titles: []TitleNode{
+			{Name: "🔥 最常播放", Items: referencedItems(most[:min(len(most), limit)], items)},
+			{Name: "🕘 最近播放", Items: referencedItems(recent[:min(len(recent), limit)], items)},
+			{Name: "🆕 从未播放", Items: referencedItems(never, items)},
+		},
+	}
+}
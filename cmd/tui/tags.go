@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ayazumi/biliCLI/internal/tags"
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// ========== 标签 ==========
+// t 编辑光标所在条目、标题或分组的标签，有标记时为全部标记的条目添加 / 移除标签；
+// 子节点继承父节点的标签。F 按标签表达式筛选目录树，如 piano & !live
+
+// shownTitle 是筛选后仍显示的标题，idx 为它在分组中的下标
+type shownTitle struct {
+	TitleNode
+	idx int
+}
+
+// shownTitles 返回分组中符合标签筛选的标题，标题下只保留符合的条目
+func (m *model) shownTitles(g GroupNode) []shownTitle {
+	titles := make([]shownTitle, 0, len(g.Titles))
+	for ti, t := range g.Titles {
+		if m.tagFilter == nil {
+			titles = append(titles, shownTitle{t, ti})
+			continue
+		}
+		var items []Item
+		for _, item := range t.Items {
+			if m.tagFilter.Match(m.itemTagSet(item.CID)) {
+				items = append(items, item)
+			}
+		}
+		if len(items) > 0 {
+			t.Items = items
+			titles = append(titles, shownTitle{t, ti})
+		}
+	}
+	return titles
+}
+
+// itemTagSet 返回条目的全部标签：自身的，加上它在曲库中所在标题和分组的
+func (m *model) itemTagSet(cid uint64) map[string]bool {
+	set := make(map[string]bool)
+	for _, t := range m.tags.Items[cid] {
+		set[t] = true
+	}
+	if key, ok := m.titleOf[cid]; ok {
+		g := m.groups[key[0]]
+		for _, t := range m.tags.Titles[titleKey(g, g.Titles[key[1]])] {
+			set[t] = true
+		}
+		for _, t := range m.tags.Groups[g.Name] {
+			set[t] = true
+		}
+	}
+	return set
+}
+
+// groupTags 和 titleTags 返回节点自身的标签，只有曲库中的分组和标题可以加标签
+func (m *model) groupTags(g GroupNode) []string {
+	if g.section != sectionLibrary {
+		return nil
+	}
+	return m.tags.Groups[g.Name]
+}
+
+func (m *model) titleTags(g GroupNode, t TitleNode) []string {
+	if g.section != sectionLibrary {
+		return nil
+	}
+	return m.tags.Titles[titleKey(g, t)]
+}
+
+// tagGlyphs 是目录树中显示的标签（不含继承的）
+func tagGlyphs(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	return " #" + strings.Join(list, " #")
+}
+
+// inheritedTags 返回节点从父节点继承的标签
+func (m *model) inheritedTags(node TreeNode) []string {
+	set := make(map[string]bool)
+	switch node.Type {
+	case NodeItem:
+		for t := range m.itemTagSet(node.CID) {
+			set[t] = true
+		}
+		for _, t := range m.tags.Items[node.CID] {
+			delete(set, t)
+		}
+	case NodeTitle:
+		for _, t := range m.groupTags(m.groups[node.groupIdx]) {
+			set[t] = true
+		}
+	}
+	list := make([]string, 0, len(set))
+	for t := range set {
+		list = append(list, t)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// openTagEditor 打开标签输入框：标记了条目时输入要添加的标签（-标签 为移除），否则编辑光标所在节点的标签
+func (m *model) openTagEditor() tea.Cmd {
+	if n := m.selected.Len(); n > 0 {
+		return m.openPromptWith(PromptTags, fmt.Sprintf("为 %d 首添加标签（-标签 为移除）: ", n), "")
+	}
+	if len(m.visibleNodes) == 0 {
+		return nil
+	}
+	node := m.visibleNodes[m.cursor]
+	if node.Type != NodeItem && m.sectionOf(node) != sectionLibrary {
+		m.pb.status = "只能为条目或曲库中的标题、分组加标签"
+		return nil
+	}
+	label := "标签"
+	if inherited := m.inheritedTags(node); len(inherited) > 0 {
+		label += "（继承: " + strings.Join(inherited, ", ") + "）"
+	}
+	m.promptNode = node
+	return m.openPromptWith(PromptTags, label+": ", strings.Join(node.tags, " "))
+}
+
+func (m *model) submitTags(value string) {
+	if m.selected.Len() > 0 {
+		cids := m.targetCIDs()
+		for _, cid := range cids {
+			m.tags.Items[cid] = editTags(m.tags.Items[cid], value)
+		}
+		m.clearMarks()
+		m.pb.status = fmt.Sprintf("已更新 %d 首的标签", len(cids))
+	} else {
+		node := m.promptNode
+		list := tags.Split(value)
+		switch node.Type {
+		case NodeItem:
+			m.tags.Items[node.CID] = list
+		case NodeTitle:
+			m.tags.Titles[m.nodeTitleKey(node)] = list
+		case NodeGroup:
+			m.tags.Groups[node.Name] = list
+		}
+		m.pb.status = "已更新标签: " + node.Name
+	}
+	m.saveTags()
+}
+
+// editTags 把 input 中的标签加入 list，带 - 前缀的从 list 中移除
+func editTags(list []string, input string) []string {
+	out := append([]string(nil), list...)
+	for _, f := range strings.Fields(strings.ReplaceAll(input, ",", " ")) {
+		if t, ok := strings.CutPrefix(f, "-"); ok {
+			t = tags.Normalize(t)
+			out = removeTag(out, t)
+			continue
+		}
+		if t := tags.Normalize(f); t != "" && !containsTag(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func removeTag(list []string, tag string) []string {
+	out := list[:0]
+	for _, t := range list {
+		if t != tag {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func containsTag(list []string, tag string) bool {
+	for _, t := range list {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *model) saveTags() {
+	for cid, list := range m.tags.Items {
+		if len(list) == 0 {
+			delete(m.tags.Items, cid)
+		}
+	}
+	for key, list := range m.tags.Titles {
+		if len(list) == 0 {
+			delete(m.tags.Titles, key)
+		}
+	}
+	for name, list := range m.tags.Groups {
+		if len(list) == 0 {
+			delete(m.tags.Groups, name)
+		}
+	}
+	m.refreshTree()
+	if m.tagsErr != nil {
+		m.pb.status = readOnlyStatus(m.tagsErr, "标签")
+		return
+	}
+	if err := userdata.SaveTags(m.tags); err != nil {
+		m.pb.status = "❗ " + err.Error()
+	}
+}
+
+// openTagFilter 打开标签筛选输入框，留空取消筛选
+func (m *model) openTagFilter() tea.Cmd {
+	return m.openPromptWith(PromptTagFilter, "标签筛选（& 且  | 或  ! 非）: ", m.tagQuery)
+}
+
+func (m *model) setTagFilter(query string) {
+	if query == "" {
+		m.tagFilter = nil
+		m.tagQuery = ""
+		m.pb.status = "已取消标签筛选"
+		m.refreshTree()
+		return
+	}
+	expr, err := tags.Parse(query)
+	if err != nil {
+		m.pb.status = "❗ " + err.Error()
+		return
+	}
+	prevFilter, prevQuery := m.tagFilter, m.tagQuery
+	m.tagFilter = expr
+	m.tagQuery = query
+	m.pb.status = ""
+	m.refreshTree()
+	// 没有任何曲目符合时保留原来的筛选，目录树不会变成空的
+	if len(m.visibleNodes) == 0 {
+		m.tagFilter, m.tagQuery = prevFilter, prevQuery
+		m.refreshTree()
+		m.pb.status = "❗ 没有符合 " + query + " 的曲目"
+	}
+}
+
+// refreshTree 重新生成目录树节点，光标超出范围时移到最后一行
+func (m *model) refreshTree() {
+	m.rebuildAllNodes()
+	m.rebuildVisible()
+	if m.cursor >= len(m.visibleNodes) {
+		m.cursor = max(len(m.visibleNodes)-1, 0)
+	}
+	m.lastMatchIdx = -1
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ayazumi/biliCLI/internal/config"
+	"github.com/ayazumi/biliCLI/internal/library"
+	"github.com/ayazumi/biliCLI/internal/userdata"
+)
+
+// ========== 裁剪开头 / 结尾的静音 ==========
+// 开启 trim.enabled 后，每首第一次播放前检测一次静音并记下结果；
+// 手动设置的裁剪位置不受开关影响，也不会被自动检测覆盖
+
+// detectTrim 在 resolveTrack 的后台任务中运行，失败时不记录，下次播放再试
+func detectTrim(cfg config.Config, file string, duration float64) *userdata.Trim {
+	start, end, err := library.DetectTrim(file, duration, cfg.Trim.NoiseDB, cfg.Trim.MinSilence)
+	if err != nil {
+		return nil
+	}
+	return &userdata.Trim{Start: start, End: end}
+}
+
+// needsTrimDetect 表示播放 cid 前需要先检测静音
+func (m *model) needsTrimDetect(cid uint64) bool {
+	_, ok := m.trims[cid]
+	return m.cfg.Trim.Enabled && !ok && !isSegment(cid)
+}
+
+// saveDetectedTrim 记录后台检测到的裁剪位置
+func (m *model) saveDetectedTrim(cid uint64, t userdata.Trim) {
+	if old, ok := m.trims[cid]; ok && old.Manual {
+		return
+	}
+	m.trims[cid] = t
+	if err := userdata.SaveTrim(cid, t); err != nil {
+		m.pb.status = "❗ " + err.Error()
+	}
+}
+
+// trimFor 返回 cid 实际生效的裁剪位置
+func (m *model) trimFor(cid uint64) (start, end time.Duration) {
+	t, ok := m.trims[cid]
+	if !ok || (!t.Manual && !m.cfg.Trim.Enabled) {
+		return 0, 0
+	}
+	return time.Duration(t.Start * float64(time.Second)), time.Duration(t.End * float64(time.Second))
+}
+
+// openTrimPrompt 为光标所在的条目手动设置裁剪位置
+func (m *model) openTrimPrompt() tea.Cmd {
+	if m.cursor >= len(m.visibleNodes) || m.visibleNodes[m.cursor].Type != NodeItem {
+		m.pb.status = "请先选中一个条目"
+		return nil
+	}
+	cid := m.visibleNodes[m.cursor].CID
+	if isSegment(cid) {
+		m.pb.status = "分段的起止由 CUE / 时间戳决定，不能裁剪"
+		return nil
+	}
+	m.promptCID = cid
+	value := ""
+	if t, ok := m.trims[cid]; ok {
+		value = formatSeconds(t.Start) + "-"
+		if t.End > 0 {
+			value += formatSeconds(t.End)
+		}
+	}
+	return m.openPromptWith(PromptTrim, "裁剪（开始-结束，留空恢复自动）: ", value)
+}
+
+func (m *model) submitTrim(cid uint64, value string) {
+	if value == "" {
+		delete(m.trims, cid)
+		if err := userdata.DeleteTrim(cid); err != nil {
+			m.pb.status = "❗ " + err.Error()
+			return
+		}
+		m.pb.status = "已恢复自动裁剪，下次播放时生效"
+		return
+	}
+
+	startStr, endStr, _ := strings.Cut(value, "-")
+	t := userdata.Trim{Manual: true}
+	var err error
+	if t.Start, err = parseClock(startStr); err != nil {
+		m.pb.status = "❗ " + err.Error()
+		return
+	}
+	if t.End, err = parseClock(endStr); err != nil {
+		m.pb.status = "❗ " + err.Error()
+		return
+	}
+	if t.End > 0 && t.End <= t.Start {
+		m.pb.status = "❗ 结束位置必须晚于开始位置"
+		return
+	}
+	m.trims[cid] = t
+	if err := userdata.SaveTrim(cid, t); err != nil {
+		m.pb.status = "❗ " + err.Error()
+		return
+	}
+	m.pb.status = "已保存裁剪位置，下次播放时生效"
+}
+
+// parseClock 解析 秒 / mm:ss / h:mm:ss（秒可带小数），空串为 0
+func parseClock(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	var total float64
+	for _, part := range strings.Split(s, ":") {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("无法识别的时间: %s", s)
+		}
+		total = total*60 + v
+	}
+	return total, nil
+}
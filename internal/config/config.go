@@ -0,0 +1,87 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Path 与 01_read_config.py / buildtree 读取的是同一个 config.json（项目根目录）
+const Path = "config.json"
+
+type Config struct {
+	Root     string         `json:"root"` // B站缓存目录
+	Shuffle  ShuffleConfig  `json:"shuffle"`
+	Audio    AudioConfig    `json:"audio"`
+	Loudness LoudnessConfig `json:"loudness"`
+	Trim     TrimConfig     `json:"trim"`
+	EQ       EQConfig       `json:"eq"`
+	Split    SplitConfig    `json:"split"`
+	Stats    StatsConfig    `json:"stats"`
+}
+
+type AudioConfig struct {
+	Device string `json:"device"` // 输出设备：default、null、pulse:<sink>、alsa:<dev>
+}
+
+type LoudnessConfig struct {
+	Target  float64 `json:"target"`  // 音量均衡的目标响度，LUFS
+	Mode    string  `json:"mode"`    // 启动时的均衡方式：off、track、album
+	Workers int     `json:"workers"` // analyze 同时运行的 ffmpeg 数，0 为 CPU 核数的一半
+}
+
+type TrimConfig struct {
+	Enabled    bool    `json:"enabled"`     // 自动跳过开头和结尾的静音
+	NoiseDB    float64 `json:"noise_db"`    // 低于这个音量算静音，dB
+	MinSilence float64 `json:"min_silence"` // 至少持续这么久才算静音，秒
+}
+
+type SplitConfig struct {
+	MinGap  float64 `json:"min_gap"`  // 至少持续这么久的静音才作为分段边界，秒
+	NoiseDB float64 `json:"noise_db"` // 低于这个音量算静音，dB
+}
+
+type StatsConfig struct {
+	ShowCounts bool `json:"show_counts"` // 启动时在目录树中显示播放次数
+	Limit      int  `json:"limit"`       // "最常播放"、"最近播放"列出的曲目数
+}
+
+type EQConfig struct {
+	Preset  string              `json:"preset"`  // 启动时使用的预设
+	Presets map[string][]EQBand `json:"presets"` // 自定义预设，与内置预设（flat、bass、vocal）同名时覆盖
+}
+
+// EQBand 对应一个 ffmpeg equalizer 滤镜
+type EQBand struct {
+	Freq  float64 `json:"freq"`  // 中心频率，Hz
+	Gain  float64 `json:"gain"`  // 增益，dB
+	Width float64 `json:"width"` // Q 值，0 为 1
+}
+
+type ShuffleConfig struct {
+	NoRepeatWindow int `json:"no_repeat_window"` // 加权随机：最近 N 首内不重复
+}
+
+// Default 返回各可选项的默认值，config.json 中没写的字段保持默认
+func Default() Config {
+	return Config{
+		Shuffle:  ShuffleConfig{NoRepeatWindow: 10},
+		Loudness: LoudnessConfig{Target: -14, Mode: "off"},
+		Trim:     TrimConfig{NoiseDB: -50, MinSilence: 1},
+		EQ:       EQConfig{Preset: "flat"},
+		Split:    SplitConfig{MinGap: 2, NoiseDB: -40},
+		Stats:    StatsConfig{Limit: 50},
+	}
+}
+
+func Load() (Config, error) {
+	cfg := Default()
+	data, err := os.ReadFile(Path)
+	if err != nil {
+		return cfg, fmt.Errorf("读取 %s 失败: %w", Path, err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("解析 %s 失败: %w", Path, err)
+	}
+	return cfg, nil
+}
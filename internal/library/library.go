@@ -0,0 +1,69 @@
+package library
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// B站缓存的 m4s 开头有 9 个字节的填充，ffmpeg 需要跳过它们才能识别
+const HeaderSize = 9
+
+var (
+	mu    sync.Mutex
+	cache = make(map[uint64]string)
+)
+
+// Input 返回 ffmpeg 系列工具可直接打开的输入：subfile 跳过填充且保留 seek 能力
+func Input(file string) string {
+	return fmt.Sprintf("subfile,,start,%d,end,0,,:%s", HeaderSize, file)
+}
+
+// FindAudio 在 root/<cid> 下找到音频分片（与 play 脚本一样跳过视频分片），结果会被缓存
+func FindAudio(root string, cid uint64) (string, error) {
+	mu.Lock()
+	file, ok := cache[cid]
+	mu.Unlock()
+	if ok {
+		return file, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(root, strconv.FormatUint(cid, 10), "*-*.m4s"))
+	if err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		if isAudio(f) {
+			mu.Lock()
+			cache[cid] = f
+			mu.Unlock()
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("未找到音频文件: %d", cid)
+}
+
+func isAudio(file string) bool {
+	out, err := exec.Command("ffprobe", "-v", "error", "-select_streams", "a",
+		"-show_entries", "stream=codec_type", "-of", "csv=p=0", Input(file)).Output()
+	return err == nil && bytes.Contains(out, []byte("audio"))
+}
+
+// ExportAudio 把音频分片去掉填充后另存为 dst（不重新编码），end 为 0 时一直到结尾
+func ExportAudio(file, dst string, start, end float64) error {
+	args := []string{"-y", "-v", "error"}
+	if start > 0 {
+		args = append(args, "-ss", fmt.Sprintf("%.3f", start))
+	}
+	if end > 0 {
+		args = append(args, "-to", fmt.Sprintf("%.3f", end))
+	}
+	args = append(args, "-i", Input(file), "-vn", "-c:a", "copy", dst)
+	if out, err := exec.Command("ffmpeg", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("导出 %s 失败: %w: %s", dst, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MeasureLoudness 用 ffmpeg 的 loudnorm 滤镜（EBU R128）测量整首的综合响度（LUFS）和真峰值（dBTP）
+func MeasureLoudness(file string) (integrated, peak float64, err error) {
+	cmd := exec.Command("ffmpeg", "-hide_banner", "-nostats", "-i", Input(file),
+		"-vn", "-af", "loudnorm=print_format=json", "-f", "null", "-")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, 0, fmt.Errorf("ffmpeg 测量失败: %w", err)
+	}
+
+	// 测量结果是输出末尾的一段 JSON，数值以字符串表示
+	start, end := strings.LastIndexByte(string(out), '{'), strings.LastIndexByte(string(out), '}')
+	if start < 0 || end < start {
+		return 0, 0, fmt.Errorf("未找到 loudnorm 测量结果")
+	}
+	var result struct {
+		InputI  string `json:"input_i"`
+		InputTP string `json:"input_tp"`
+	}
+	if err := json.Unmarshal(out[start:end+1], &result); err != nil {
+		return 0, 0, fmt.Errorf("解析 loudnorm 结果失败: %w", err)
+	}
+	integrated, err1 := strconv.ParseFloat(result.InputI, 64)
+	peak, err2 := strconv.ParseFloat(result.InputTP, 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("无法识别的 loudnorm 结果: %s / %s", result.InputI, result.InputTP)
+	}
+	if math.IsInf(integrated, 0) || math.IsNaN(integrated) {
+		return 0, 0, fmt.Errorf("整首为静音")
+	}
+	return integrated, peak, nil
+}
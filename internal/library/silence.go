@@ -0,0 +1,113 @@
+package library
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// 结尾处的静音与曲目结尾相差不超过这个值就算"到结尾"
+const silenceEndSlack = 0.5
+
+// 检测开头 / 结尾静音时只解码开头和结尾各这么多秒，避免播放前解码整个文件
+const trimWindow = 60.0
+
+var (
+	silenceStartRe = regexp.MustCompile(`silence_start: (-?[0-9.]+)`)
+	silenceEndRe   = regexp.MustCompile(`silence_end: (-?[0-9.]+)`)
+)
+
+// Silence 是一段静音的起止（秒），End 为 -1 表示一直持续到文件结尾
+type Silence struct {
+	Start, End float64
+}
+
+// DetectSilence 用 ffmpeg 的 silencedetect 找出所有低于 noiseDB、持续至少 minSilence 秒的静音
+func DetectSilence(file string, noiseDB, minSilence float64) ([]Silence, error) {
+	return detectSilence(file, nil, noiseDB, minSilence)
+}
+
+// detectSilence 同 DetectSilence，inputArgs 放在 -i 之前，用于只解码文件的一部分；
+// 此时返回的时间相对于解码的起点
+func detectSilence(file string, inputArgs []string, noiseDB, minSilence float64) ([]Silence, error) {
+	af := fmt.Sprintf("silencedetect=noise=%gdB:d=%g", noiseDB, minSilence)
+	args := append([]string{"-hide_banner", "-nostats"}, inputArgs...)
+	args = append(args, "-i", Input(file), "-vn", "-af", af, "-f", "null", "-")
+	out, err := exec.Command("ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg 静音检测失败: %w", err)
+	}
+
+	starts := silenceStartRe.FindAllSubmatch(out, -1)
+	ends := silenceEndRe.FindAllSubmatch(out, -1)
+	silences := make([]Silence, len(starts))
+	for i, m := range starts {
+		silences[i].Start, _ = strconv.ParseFloat(string(m[1]), 64)
+		silences[i].End = -1
+		if i < len(ends) {
+			silences[i].End, _ = strconv.ParseFloat(string(ends[i][1]), 64)
+		}
+	}
+	return silences, nil
+}
+
+// DetectTrim 找出开头和结尾的静音，返回有声部分的起止时间（秒）；
+// end 为 0 表示结尾不需要裁剪。duration 为曲目时长，未知时传 0。
+// 时长已知且足够长时只解码开头和结尾各 trimWindow 秒，否则解码整个文件
+func DetectTrim(file string, duration, noiseDB, minSilence float64) (start, end float64, err error) {
+	if duration <= 2*trimWindow {
+		silences, err := DetectSilence(file, noiseDB, minSilence)
+		if err != nil || len(silences) == 0 {
+			return 0, 0, err
+		}
+		return trimFromSilences(silences, silences, duration)
+	}
+
+	window := strconv.FormatFloat(trimWindow, 'f', -1, 64)
+	head, err := detectSilence(file, []string{"-t", window}, noiseDB, minSilence)
+	if err != nil {
+		return 0, 0, err
+	}
+	tail, err := detectSilence(file, []string{"-sseof", "-" + window}, noiseDB, minSilence)
+	if err != nil {
+		return 0, 0, err
+	}
+	// 开头窗口里一直持续到窗口末尾的静音并不是真正的结尾，不能当作有声部分的起点
+	for i := range head {
+		if head[i].End < 0 {
+			head[i].End = 0
+		}
+	}
+	// 结尾窗口的时间相对于窗口起点，换算回整首的时间
+	offset := duration - trimWindow
+	for i := range tail {
+		tail[i].Start += offset
+		if tail[i].End >= 0 {
+			tail[i].End += offset
+		}
+	}
+	return trimFromSilences(head, tail, duration)
+}
+
+// trimFromSilences 根据开头部分和结尾部分的静音算出有声部分的起止
+func trimFromSilences(head, tail []Silence, duration float64) (start, end float64, err error) {
+	// 开头：第一段静音从 0 开始
+	if len(head) > 0 {
+		if first := head[0]; first.Start <= 0.1 && first.End > 0 {
+			start = first.End
+		}
+	}
+	// 结尾：最后一段静音一直到文件末尾，或结束于曲目结尾
+	if len(tail) > 0 {
+		last := tail[len(tail)-1]
+		if last.End < 0 || (duration > 0 && last.End >= duration-silenceEndSlack) {
+			end = last.Start
+		}
+	}
+	if end > 0 && end <= start {
+		// 整首都是静音，不裁剪
+		return 0, 0, nil
+	}
+	return start, end, nil
+}
@@ -12,13 +12,13 @@ type Model struct {
 	fullTree     []GroupNode
 	visibleNodes []tree.Node
 	cursor       int
-	selected     map[uint64]bool
+	selected     Selection
 	searchQuery  string
 }
 
 func NewModel() *Model {
 	return &Model{
-		selected: make(map[uint64]bool),
+		selected: NewSelection(),
 	}
 }
 
@@ -174,18 +174,25 @@ func (m *Model) ToggleSelection(cursor int) {
 
 	node := m.visibleNodes[cursor]
 	if node.IsLeaf() {
-		if m.selected[node.CID] {
-			delete(m.selected, node.CID)
-		} else {
-			m.selected[node.CID] = true
-		}
+		m.selected.Toggle(node.CID)
 	}
 }
 
+func (m *Model) IsSelected(cid uint64) bool {
+	return m.selected.Has(cid)
+}
+
+// GetSelectedCIDs 按目录树中的顺序返回选中的 CID
 func (m *Model) GetSelectedCIDs() []uint64 {
-	var cids []uint64
-	for cid := range m.selected {
-		cids = append(cids, cid)
+	var order []uint64
+	for _, g := range m.fullTree {
+		for _, t := range g.Titles {
+			for _, tab := range t.Tabs {
+				for _, item := range tab.Items {
+					order = append(order, item.CID)
+				}
+			}
+		}
 	}
-	return cids
+	return m.selected.InOrder(order)
 }
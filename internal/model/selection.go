@@ -0,0 +1,47 @@
+package model
+
+// Selection 记录被标记的叶子节点（CID）；取出时按调用方给出的树顺序排列，而不是 map 的随机顺序
+type Selection map[uint64]bool
+
+func NewSelection() Selection {
+	return make(Selection)
+}
+
+func (s Selection) Toggle(cid uint64) {
+	s.Set(cid, !s[cid])
+}
+
+func (s Selection) Set(cid uint64, on bool) {
+	if on {
+		s[cid] = true
+	} else {
+		delete(s, cid)
+	}
+}
+
+func (s Selection) Has(cid uint64) bool {
+	return s[cid]
+}
+
+func (s Selection) Len() int {
+	return len(s)
+}
+
+func (s Selection) Clear() {
+	for cid := range s {
+		delete(s, cid)
+	}
+}
+
+// InOrder 按 order（通常是目录树中的先后顺序）返回被标记的 CID，每个只出现一次
+func (s Selection) InOrder(order []uint64) []uint64 {
+	var cids []uint64
+	seen := make(map[uint64]bool, len(s))
+	for _, cid := range order {
+		if s[cid] && !seen[cid] {
+			cids = append(cids, cid)
+			seen[cid] = true
+		}
+	}
+	return cids
+}
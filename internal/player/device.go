@@ -0,0 +1,95 @@
+package player
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"strings"
+)
+
+// ffplay 通过 SDL 输出音频，设备用环境变量选择：
+//
+//	""/"default"    SDL 默认设备
+//	"null"          SDL_AUDIODRIVER=dummy，无声输出（无头环境）
+//	"pulse:<sink>"  PulseAudio / PipeWire 的 sink（PULSE_SINK）
+//	"alsa:<dev>"    ALSA 设备（AUDIODEV）
+type Device struct {
+	ID          string
+	Description string
+}
+
+// ListDevices 列出当前系统可用的输出设备
+func ListDevices() []Device {
+	devices := []Device{
+		{ID: "default", Description: "系统默认"},
+		{ID: "null", Description: "无声输出（dummy）"},
+	}
+	devices = append(devices, pulseSinks()...)
+	devices = append(devices, alsaDevices()...)
+	return devices
+}
+
+// pactl list short sinks 每行：<序号>\t<名称>\t<模块>\t<格式>\t<状态>
+func pulseSinks() []Device {
+	out, err := exec.Command("pactl", "list", "short", "sinks").Output()
+	if err != nil {
+		return nil
+	}
+	var devices []Device
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		fields := strings.Split(sc.Text(), "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		devices = append(devices, Device{ID: "pulse:" + fields[1], Description: "PulseAudio " + fields[1]})
+	}
+	return devices
+}
+
+// aplay -L 中不以空白开头的行是设备名，下一行缩进的是说明
+func alsaDevices() []Device {
+	out, err := exec.Command("aplay", "-L").Output()
+	if err != nil {
+		return nil
+	}
+	var devices []Device
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if n := len(devices); n > 0 && devices[n-1].Description == "" {
+				devices[n-1].Description = "ALSA " + strings.TrimSpace(line)
+			}
+			continue
+		}
+		if line == "null" || line == "default" {
+			continue
+		}
+		devices = append(devices, Device{ID: "alsa:" + line})
+	}
+	for i := range devices {
+		if devices[i].Description == "" {
+			devices[i].Description = "ALSA " + strings.TrimPrefix(devices[i].ID, "alsa:")
+		}
+	}
+	return devices
+}
+
+// deviceEnv 返回选择设备 id 所需的环境变量
+func deviceEnv(id string) []string {
+	switch {
+	case id == "" || id == "default":
+		return nil
+	case id == "null":
+		return []string{"SDL_AUDIODRIVER=dummy"}
+	case strings.HasPrefix(id, "pulse:"):
+		return []string{"SDL_AUDIODRIVER=pulseaudio", "PULSE_SINK=" + strings.TrimPrefix(id, "pulse:")}
+	case strings.HasPrefix(id, "alsa:"):
+		return []string{"SDL_AUDIODRIVER=alsa", "AUDIODEV=" + strings.TrimPrefix(id, "alsa:")}
+	}
+	return nil
+}
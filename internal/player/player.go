@@ -0,0 +1,281 @@
+package player
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ayazumi/biliCLI/internal/library"
+)
+
+// Track 描述一首待播放的曲目
+type Track struct {
+	CID      uint64
+	Name     string
+	File     string // 音频 m4s 路径
+	Duration time.Duration
+	Filters  string        // 整首使用的 -af 滤镜链（如音量均衡）
+	Base     time.Duration // 分段曲目在文件中的起点，其余时间都相对于它
+	Start    time.Duration // 跳过开头的静音：早于 Start 的位置都从 Start 播放
+	End      time.Duration // 跳过结尾的静音：播放到 End 即结束，0 为播放到文件结尾
+}
+
+// Player 在后台运行 ffplay，不占用终端的输入输出。
+// 暂停用 SIGSTOP / SIGCONT，跳转和更换滤镜则在新位置重启 ffplay。
+type Player struct {
+	mu       sync.Mutex
+	procs    *registry
+	track    Track
+	loaded   bool
+	proc     *process
+	viz      *visualizer
+	filters  string        // -af 滤镜链
+	device   string        // 当前曲目使用的输出设备
+	next     string        // 下一首起使用的输出设备
+	offset   time.Duration // 本次 ffplay 的起点（-ss）
+	started  time.Time     // 本次 ffplay 的启动时刻，恢复播放时会扣掉暂停的时长
+	paused   bool
+	pausedAt time.Duration
+}
+
+type process struct {
+	cmd    *exec.Cmd
+	done   chan struct{}
+	killed bool // 由 Player 主动结束，而不是播放到结尾
+}
+
+// New 创建播放器，子进程的进程组记录在 runDir 下（为空则不记录）
+func New(runDir string) *Player {
+	return &Player{procs: newRegistry(runDir)}
+}
+
+// Play 停止当前曲目，从 at 处开始播放 t
+func (p *Player) Play(t Track, at time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopLocked()
+	p.track = t
+	p.loaded = true
+	p.filters = t.Filters
+	p.device = p.next
+	if err := p.startLocked(at); err != nil {
+		p.loaded = false
+		return err
+	}
+	p.viz = startVisualizer(t.File, t.Name, p.procs)
+	return nil
+}
+
+// Stop 结束当前曲目
+func (p *Player) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopLocked()
+}
+
+func (p *Player) stopLocked() {
+	p.killLocked()
+	if p.viz != nil {
+		p.viz.stop()
+		p.viz = nil
+	}
+	p.loaded = false
+	p.paused = false
+}
+
+func (p *Player) startLocked(at time.Duration) error {
+	if at < p.track.Start {
+		at = p.track.Start
+	}
+	args := []string{"-v", "0", "-nostats", "-nodisp", "-autoexit",
+		"-ss", fmt.Sprintf("%.3f", (p.track.Base + at).Seconds())}
+	if p.track.End > 0 {
+		args = append(args, "-t", fmt.Sprintf("%.3f", max(p.track.End-at, 0).Seconds()))
+	}
+	if p.filters != "" {
+		args = append(args, "-af", p.filters)
+	}
+	args = append(args, "-i", library.Input(p.track.File))
+
+	cmd := exec.Command("ffplay", args...)
+	if env := deviceEnv(p.device); env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	setpgid(cmd)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动 ffplay 失败: %w", err)
+	}
+	pid := cmd.Process.Pid
+	p.procs.add(pid, "ffplay")
+	proc := &process{cmd: cmd, done: make(chan struct{})}
+	go func() {
+		cmd.Wait()
+		p.procs.remove(pid)
+		close(proc.done)
+	}()
+
+	p.proc = proc
+	p.offset = at
+	p.started = time.Now()
+	p.paused = false
+	return nil
+}
+
+// signal 发给 ffplay 所在的整个进程组
+func (pr *process) signal(sig syscall.Signal) {
+	signalGroup(pr.cmd.Process.Pid, sig)
+}
+
+func (p *Player) killLocked() {
+	if p.proc == nil {
+		return
+	}
+	p.proc.killed = true
+	if p.paused {
+		p.proc.signal(syscall.SIGCONT)
+	}
+	p.proc.signal(syscall.SIGKILL)
+	<-p.proc.done
+	p.proc = nil
+}
+
+// restartLocked 在 at 处重新启动 ffplay，保持暂停状态
+func (p *Player) restartLocked(at time.Duration) error {
+	paused := p.paused
+	p.killLocked()
+	if err := p.startLocked(at); err != nil {
+		p.loaded = false
+		return err
+	}
+	if paused {
+		p.proc.signal(syscall.SIGSTOP)
+		p.paused = true
+		p.pausedAt = at
+	}
+	return nil
+}
+
+// SetDevice 选择输出设备（见 Device），从下一首开始生效
+func (p *Player) SetDevice(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.next = id
+}
+
+// Device 返回下一首将使用的输出设备
+func (p *Player) Device() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.next
+}
+
+// Seek 跳转到 at
+func (p *Player) Seek(at time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.loaded {
+		return nil
+	}
+	return p.restartLocked(at)
+}
+
+// SetFilters 更换 ffplay 的 -af 滤镜链，从当前位置重新开始
+func (p *Player) SetFilters(af string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.filters == af {
+		return nil
+	}
+	p.filters = af
+	if !p.loaded {
+		return nil
+	}
+	return p.restartLocked(p.positionLocked())
+}
+
+// TogglePause 暂停 / 继续，返回切换后是否处于暂停
+func (p *Player) TogglePause() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.proc == nil {
+		return false
+	}
+	if p.paused {
+		p.proc.signal(syscall.SIGCONT)
+		p.viz.signal(syscall.SIGCONT)
+		p.started = time.Now().Add(-(p.pausedAt - p.offset))
+		p.paused = false
+	} else {
+		p.pausedAt = p.positionLocked()
+		p.proc.signal(syscall.SIGSTOP)
+		p.viz.signal(syscall.SIGSTOP)
+		p.paused = true
+	}
+	return p.paused
+}
+
+func (p *Player) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// Position 返回当前播放位置
+func (p *Player) Position() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.positionLocked()
+}
+
+func (p *Player) positionLocked() time.Duration {
+	if !p.loaded {
+		return 0
+	}
+	if p.paused {
+		return p.pausedAt
+	}
+	pos := p.offset + time.Since(p.started)
+	if p.track.End > 0 && pos > p.track.End {
+		pos = p.track.End
+	}
+	if p.track.Duration > 0 && pos > p.track.Duration {
+		pos = p.track.Duration
+	}
+	return pos
+}
+
+// Track 返回当前曲目；没有曲目时 ok 为 false
+func (p *Player) Track() (t Track, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.track, p.loaded
+}
+
+// Ended 表示 ffplay 已经自己播放到结尾退出
+func (p *Player) Ended() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.loaded || p.proc == nil {
+		return false
+	}
+	select {
+	case <-p.proc.done:
+		return !p.proc.killed
+	default:
+		return false
+	}
+}
+
+// HexLines 返回氛围刷屏最近的几行
+func (p *Player) HexLines() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.viz == nil {
+		return nil
+	}
+	return p.viz.lines()
+}
@@ -0,0 +1,149 @@
+package player
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 每个子进程（ffplay、fake_hex）单独一个进程组，按组发信号，连同 xxd 等孙进程一起处理；
+// 组号记录在 <dir>/<本进程 PID>.json，异常退出后由下次启动（或 launch）据此清理，
+// 不再用 pkill 误杀别人的进程。
+// exec 自身（如重新构建后重启）时 PID 不变，所以文件中另记一个每次运行不同的 token，
+// PID 相同而 token 不同的文件属于 exec 之前的进程，同样要清理。
+
+// runToken 标识本次运行，exec 后的新程序会重新生成
+var runToken = fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+
+// 子进程都以自身 PID 为进程组号
+func setpgid(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func signalGroup(pid int, sig syscall.Signal) {
+	syscall.Kill(-pid, sig)
+}
+
+type child struct {
+	PID  int    `json:"pid"`
+	Name string `json:"name"` // 进程名，清理前用来核对 PID 没有被复用
+}
+
+type pidFile struct {
+	Owner    int     `json:"owner"`
+	Token    string  `json:"token"`
+	Children []child `json:"children"`
+}
+
+// registry 维护本进程的 PID 文件
+type registry struct {
+	mu       sync.Mutex
+	path     string
+	children map[int]string
+}
+
+func newRegistry(dir string) *registry {
+	if dir == "" {
+		return nil
+	}
+	return &registry{
+		path:     filepath.Join(dir, fmt.Sprintf("%d.json", os.Getpid())),
+		children: make(map[int]string),
+	}
+}
+
+func (r *registry) add(pid int, name string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.children[pid] = name
+	r.write()
+}
+
+func (r *registry) remove(pid int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.children, pid)
+	r.write()
+}
+
+// write 调用方需持有锁
+func (r *registry) write() {
+	if len(r.children) == 0 {
+		os.Remove(r.path)
+		return
+	}
+	pf := pidFile{Owner: os.Getpid(), Token: runToken}
+	for pid, name := range r.children {
+		pf.Children = append(pf.Children, child{PID: pid, Name: name})
+	}
+	data, err := json.Marshal(pf)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return
+	}
+	tmp := r.path + ".tmp"
+	if os.WriteFile(tmp, data, 0o644) == nil {
+		os.Rename(tmp, r.path)
+	}
+}
+
+// CleanupStale 结束 dir 中记录的、所属进程已不存在的子进程组（崩溃或被 SIGKILL 后残留）
+func CleanupStale(dir string) {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		var pf pidFile
+		if err := json.Unmarshal(data, &pf); err != nil {
+			os.Remove(f)
+			continue
+		}
+		if pf.Owner == os.Getpid() {
+			if pf.Token == runToken {
+				continue
+			}
+		} else if alive(pf.Owner) {
+			continue
+		}
+		for _, c := range pf.Children {
+			if processName(c.PID) == c.Name {
+				signalGroup(c.PID, syscall.SIGCONT)
+				signalGroup(c.PID, syscall.SIGKILL)
+			}
+		}
+		os.Remove(f)
+	}
+}
+
+func alive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+func processName(pid int) string {
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid)); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	// 没有 /proc（macOS）时退回 ps
+	out, err := exec.Command("ps", "-o", "comm=", "-p", fmt.Sprint(pid)).Output()
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(strings.TrimSpace(string(out)))
+}
@@ -0,0 +1,84 @@
+package player
+
+import (
+	"bufio"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 保留的刷屏行数
+const hexLines = 4
+
+// visualizer 运行 fake_hex，把它写到终端的刷屏内容收集起来交给 TUI 显示
+type visualizer struct {
+	cmd   *exec.Cmd
+	procs *registry
+	done  chan struct{}
+
+	mu  sync.Mutex
+	buf []string
+}
+
+func startVisualizer(file, name string, procs *registry) *visualizer {
+	cmd := exec.Command("./fake_hex", file, name)
+	setpgid(cmd)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil
+	}
+	if err := cmd.Start(); err != nil {
+		return nil
+	}
+	procs.add(cmd.Process.Pid, "fake_hex")
+	v := &visualizer{cmd: cmd, procs: procs, done: make(chan struct{})}
+	go v.read(bufio.NewScanner(out))
+	return v
+}
+
+// fake_hex 每行输出形如 "\r<状态栏>\r<hex>"，只取最后一个 \r 之后的部分
+func (v *visualizer) read(sc *bufio.Scanner) {
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.LastIndexByte(line, '\r'); i >= 0 {
+			line = line[i+1:]
+		}
+		v.mu.Lock()
+		v.buf = append(v.buf, line)
+		if len(v.buf) > hexLines {
+			v.buf = v.buf[len(v.buf)-hexLines:]
+		}
+		v.mu.Unlock()
+	}
+	v.cmd.Wait()
+	v.procs.remove(v.cmd.Process.Pid)
+	close(v.done)
+}
+
+func (v *visualizer) lines() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return append([]string(nil), v.buf...)
+}
+
+// signal 发给 fake_hex 所在的整个进程组（包括它启动的 xxd）
+func (v *visualizer) signal(sig syscall.Signal) {
+	if v == nil {
+		return
+	}
+	signalGroup(v.cmd.Process.Pid, sig)
+}
+
+// stop 先让 fake_hex 自己退出，超时再强制结束整个进程组
+func (v *visualizer) stop() {
+	v.signal(syscall.SIGCONT)
+	v.signal(syscall.SIGTERM)
+	select {
+	case <-v.done:
+	case <-time.After(500 * time.Millisecond):
+		v.signal(syscall.SIGKILL)
+		<-v.done
+	}
+}
@@ -0,0 +1,256 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ========== 智能播放列表的查询语言 ==========
+// 查询由空格分隔的条件组成，全部满足才算匹配；条件前加 - 表示取反，值中有空格时用双引号括起来：
+//
+//	duration>180 duration<600 group:"东方" rating>=4 -played:7d
+//
+// 支持的条件：
+//	duration  时长，秒或 3:00、3m 这样的写法，可用 > < >= <= =
+//	rating    评分 0-5；plays、skips 听完 / 跳过的次数，比较方式同上
+//	group、title、name  分组名、标题名、分P名包含该文字（= 为完全相同）
+//	tag       带有该标签（含继承的）
+//	is:fav    已收藏
+//	played    played:7d 或 played<7d 为最近 7 天内播放过，played>7d 为超过 7 天没播放（含从未播放）
+//	其他文字  分组名、标题名或分P名包含该文字
+
+// Fields 是一首曲目可供查询的信息
+type Fields struct {
+	Group     string
+	Title     string
+	Name      string // 分P名
+	Duration  float64
+	Rating    int
+	Favourite bool
+	Plays     int
+	Skips     int
+	// LastPlayed 为零值表示从未播放
+	LastPlayed time.Time
+	Tags       map[string]bool
+}
+
+// Query 是解析后的查询
+type Query struct {
+	terms []term
+}
+
+type term struct {
+	neg   bool
+	match func(f Fields, now time.Time) bool
+}
+
+// Match 判断曲目是否满足全部条件
+func (q Query) Match(f Fields, now time.Time) bool {
+	for _, t := range q.terms {
+		if t.match(f, now) == t.neg {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse 解析查询，空查询匹配全部曲目
+func Parse(s string) (Query, error) {
+	words, err := split(s)
+	if err != nil {
+		return Query{}, err
+	}
+	var q Query
+	for _, w := range words {
+		t, err := parseTerm(w)
+		if err != nil {
+			return Query{}, err
+		}
+		q.terms = append(q.terms, t)
+	}
+	return q, nil
+}
+
+// split 按空白拆分，双引号中的空白保留，引号本身去掉
+func split(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	quoted, started := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case unicode.IsSpace(r) && !quoted:
+			if started {
+				words = append(words, cur.String())
+				cur.Reset()
+				started = false
+			}
+		default:
+			cur.WriteRune(r)
+			started = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("查询中的引号没有配对")
+	}
+	if started {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// 较长的运算符在前，避免 >= 被当成 >
+var operators = []string{">=", "<=", ":", ">", "<", "="}
+
+func parseTerm(w string) (term, error) {
+	t := term{}
+	if len(w) > 1 && w[0] == '-' {
+		t.neg = true
+		w = w[1:]
+	}
+	key, op, value := "", "", ""
+	i := strings.IndexFunc(w, func(r rune) bool { return !unicode.IsLetter(r) })
+	for _, o := range operators {
+		if i > 0 && strings.HasPrefix(w[i:], o) {
+			key, op, value = strings.ToLower(w[:i]), o, w[i+len(o):]
+			break
+		}
+	}
+	if op == "" {
+		text := strings.ToLower(w)
+		t.match = func(f Fields, _ time.Time) bool {
+			return contains(f.Group, text) || contains(f.Title, text) || contains(f.Name, text)
+		}
+		return t, nil
+	}
+
+	var err error
+	switch key {
+	case "duration", "dur":
+		var d float64
+		if d, err = parseLength(value); err == nil {
+			t.match = compare(op, d, func(f Fields) float64 { return f.Duration })
+		}
+	case "rating", "plays", "skips":
+		var n int
+		if n, err = strconv.Atoi(value); err != nil {
+			err = fmt.Errorf("%s 需要整数: %q", key, value)
+			break
+		}
+		get := map[string]func(Fields) float64{
+			"rating": func(f Fields) float64 { return float64(f.Rating) },
+			"plays":  func(f Fields) float64 { return float64(f.Plays) },
+			"skips":  func(f Fields) float64 { return float64(f.Skips) },
+		}[key]
+		t.match = compare(op, float64(n), get)
+	case "group", "title", "name":
+		t.match, err = text(key, op, value)
+	case "tag":
+		tag := strings.ToLower(strings.TrimPrefix(value, "#"))
+		t.match = func(f Fields, _ time.Time) bool { return f.Tags[tag] }
+	case "is":
+		switch strings.ToLower(value) {
+		case "fav", "favourite", "favorite":
+			t.match = func(f Fields, _ time.Time) bool { return f.Favourite }
+		default:
+			err = fmt.Errorf("未知的 is:%s", value)
+		}
+	case "played":
+		var d time.Duration
+		if d, err = parseAge(value); err != nil {
+			break
+		}
+		within := func(f Fields, now time.Time) bool {
+			return !f.LastPlayed.IsZero() && now.Sub(f.LastPlayed) <= d
+		}
+		switch op {
+		case ":", "<", "<=":
+			t.match = within
+		case ">", ">=":
+			t.match = func(f Fields, now time.Time) bool { return !within(f, now) }
+		default:
+			err = fmt.Errorf("played 不支持 %s", op)
+		}
+	default:
+		err = fmt.Errorf("未知的条件: %s", key)
+	}
+	return t, err
+}
+
+func contains(s, lowerSub string) bool {
+	return strings.Contains(strings.ToLower(s), lowerSub)
+}
+
+func compare(op string, n float64, get func(Fields) float64) func(Fields, time.Time) bool {
+	var cmp func(v float64) bool
+	switch op {
+	case ">":
+		cmp = func(v float64) bool { return v > n }
+	case "<":
+		cmp = func(v float64) bool { return v < n }
+	case ">=":
+		cmp = func(v float64) bool { return v >= n }
+	case "<=":
+		cmp = func(v float64) bool { return v <= n }
+	default:
+		cmp = func(v float64) bool { return v == n }
+	}
+	return func(f Fields, _ time.Time) bool { return cmp(get(f)) }
+}
+
+func text(key, op, value string) (func(Fields, time.Time) bool, error) {
+	get := map[string]func(Fields) string{
+		"group": func(f Fields) string { return f.Group },
+		"title": func(f Fields) string { return f.Title },
+		"name":  func(f Fields) string { return f.Name },
+	}[key]
+	switch op {
+	case ":":
+		lower := strings.ToLower(value)
+		return func(f Fields, _ time.Time) bool { return contains(get(f), lower) }, nil
+	case "=":
+		return func(f Fields, _ time.Time) bool { return strings.EqualFold(get(f), value) }, nil
+	}
+	return nil, fmt.Errorf("%s 不支持 %s", key, op)
+}
+
+// parseLength 解析时长：180、3:00、1:02:03、3m、90s、1h
+func parseLength(s string) (float64, error) {
+	if strings.Contains(s, ":") {
+		var total float64
+		for _, part := range strings.Split(s, ":") {
+			n, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				return 0, fmt.Errorf("无法解析时长: %q", s)
+			}
+			total = total*60 + n
+		}
+		return total, nil
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return n, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("无法解析时长: %q", s)
+	}
+	return d.Seconds(), nil
+}
+
+// parseAge 解析 played 的时间范围：30m、12h、7d、2w
+func parseAge(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if len(s) > 1 {
+		if unit, ok := units[s[len(s)-1]]; ok {
+			if n, err := strconv.ParseFloat(s[:len(s)-1], 64); err == nil {
+				return time.Duration(n * float64(unit)), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("无法解析时间范围: %q（如 12h、7d、2w）", s)
+}
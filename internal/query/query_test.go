@@ -0,0 +1,105 @@
+package query
+
+import (
+	"testing"
+	"time"
+)
+
+var now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func daysAgo(n int) time.Time { return now.Add(-time.Duration(n) * 24 * time.Hour) }
+
+func TestMatch(t *testing.T) {
+	touhou := Fields{
+		Group:      "东方 Project",
+		Title:      "Bad Apple!!",
+		Name:       "feat. nomico",
+		Duration:   300,
+		Rating:     4,
+		Plays:      3,
+		LastPlayed: daysAgo(30),
+		Tags:       map[string]bool{"vocal": true},
+	}
+	tests := []struct {
+		name  string
+		query string
+		f     Fields
+		match bool
+	}{
+		{"空查询", "", Fields{}, true},
+		{"需求中的例子", `duration>180 duration<600 group:"东方" rating>=4 -played:7d`, touhou, true},
+		{"需求中的例子：最近播放过", `duration>180 duration<600 group:"东方" rating>=4 -played:7d`,
+			Fields{Group: "东方", Duration: 300, Rating: 4, LastPlayed: daysAgo(1)}, false},
+		{"需求中的例子：评分不够", `duration>180 duration<600 group:"东方" rating>=4 -played:7d`,
+			Fields{Group: "东方", Duration: 300, Rating: 3}, false},
+
+		{">= 含等于", "rating>=4", Fields{Rating: 4}, true},
+		{"> 不含等于", "rating>4", Fields{Rating: 4}, false},
+		{"<= 含等于", "plays<=3", Fields{Plays: 3}, true},
+		{"< 不含等于", "plays<3", Fields{Plays: 3}, false},
+		{"= 相等", "skips=2", Fields{Skips: 2}, true},
+
+		{"时长为秒", "duration>=180", Fields{Duration: 180}, true},
+		{"时长为 3:00", "duration>=3:00", Fields{Duration: 180}, true},
+		{"时长为 3:00 不含等于", "duration>3:00", Fields{Duration: 180}, false},
+		{"时长为 3m", "duration<3m", Fields{Duration: 179}, true},
+		{"时长为 1:02:03", "dur=1:02:03", Fields{Duration: 3723}, true},
+
+		{"取反", "-tag:live", Fields{Tags: map[string]bool{"vocal": true}}, true},
+		{"取反后不匹配", "-tag:vocal", Fields{Tags: map[string]bool{"vocal": true}}, false},
+		{"取反文字", "-apple", touhou, false},
+		{"单独的 - 是文字", "-", Fields{Name: "a - b"}, true},
+
+		{"引号中的空格", `title:"Bad Apple"`, touhou, true},
+		{"引号中的空格不匹配", `title:"Bad  Apple"`, touhou, false},
+		{"引号包住整个词", `"feat. nomico"`, touhou, true},
+		{"没有引号时值只到空格为止", "title=bad apple!!", Fields{Title: "Bad Apple!!"}, false},
+		{"= 完全相同，不区分大小写", `title="bad apple!!"`, Fields{Title: "Bad Apple!!"}, true},
+		{": 包含", "group:东方", touhou, true},
+		{"文字匹配分P名", "NOMICO", touhou, true},
+
+		{"标签带 #", "tag:#Vocal", touhou, true},
+		{"已收藏", "is:fav", Fields{Favourite: true}, true},
+		{"未收藏", "is:fav", Fields{}, false},
+
+		{"played: 为最近播放过", "played:7d", Fields{LastPlayed: daysAgo(1)}, true},
+		{"played< 同 played:", "played<7d", Fields{LastPlayed: daysAgo(8)}, false},
+		{"played> 为很久没播放", "played>7d", Fields{LastPlayed: daysAgo(8)}, true},
+		{"played> 最近播放过", "played>7d", Fields{LastPlayed: daysAgo(1)}, false},
+		{"played> 含从未播放", "played>7d", Fields{}, true},
+		{"played: 不含从未播放", "played:7d", Fields{}, false},
+		{"-played: 含从未播放", "-played:7d", Fields{}, true},
+		{"played 按小时", "played:12h", Fields{LastPlayed: now.Add(-11 * time.Hour)}, true},
+		{"played 按周", "played>2w", Fields{LastPlayed: daysAgo(10)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.query, err)
+			}
+			if got := q.Match(tt.f, now); got != tt.match {
+				t.Errorf("Parse(%q).Match = %v，期望 %v", tt.query, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		`group:"东方`,
+		"duration>abc",
+		"rating>=4.5",
+		"plays>many",
+		"is:new",
+		"played=7d",
+		"played:7",
+		"played:d",
+		"group>东方",
+		"year>2020",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) 应返回错误", s)
+		}
+	}
+}
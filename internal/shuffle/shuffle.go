@@ -0,0 +1,119 @@
+package shuffle
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Stats 是加权随机用到的曲目信息，缺失的字段按零值处理
+type Stats struct {
+	Rating     int // 0 表示未评分，否则 1-5
+	PlayCount  int
+	LastPlayed time.Time
+}
+
+// 未评分的曲目按 3 星计
+const defaultRating = 3
+
+// Weight 计算曲目被抽中的相对权重：
+// 评分越高越容易抽中，播放次数越多、距上次播放越近越不容易抽中
+func Weight(s Stats, now time.Time) float64 {
+	rating := s.Rating
+	if rating <= 0 {
+		rating = defaultRating
+	}
+	w := math.Pow(float64(rating)/defaultRating, 2)
+	w /= 1 + math.Log1p(float64(s.PlayCount))
+	if !s.LastPlayed.IsZero() {
+		// 刚播放过约为 0.1，三天后约 0.67，一周后约 0.9
+		hours := now.Sub(s.LastPlayed).Hours()
+		if hours < 0 {
+			hours = 0
+		}
+		w *= 1 - 0.9*math.Exp(-hours/72)
+	}
+	return w
+}
+
+// Picker 按权重逐首抽取，最近 window 首内不重复。
+// 给定相同的种子和输入，抽取结果完全一致。
+type Picker struct {
+	rng    *rand.Rand
+	window int
+	recent []uint64
+}
+
+func NewPicker(seed int64, window int) *Picker {
+	if window < 0 {
+		window = 0
+	}
+	return &Picker{rng: rand.New(rand.NewSource(seed)), window: window}
+}
+
+// Played 记录一次播放，用于"最近 N 首内不重复"
+func (p *Picker) Played(cid uint64) {
+	if p.window == 0 {
+		return
+	}
+	p.recent = append(p.recent, cid)
+	if len(p.recent) > p.window {
+		p.recent = p.recent[len(p.recent)-p.window:]
+	}
+}
+
+// Recent 表示 cid 在最近 window 首之内
+func (p *Picker) Recent(cid uint64) bool {
+	for _, r := range p.recent {
+		if r == cid {
+			return true
+		}
+	}
+	return false
+}
+
+// Next 从 pool 中抽取下一首；pool 比窗口还小时只排除最近的 len(pool)-1 首
+func (p *Picker) Next(pool []uint64, stats func(uint64) Stats, now time.Time) (uint64, bool) {
+	if len(pool) == 0 {
+		return 0, false
+	}
+
+	window := len(p.recent)
+	if window > len(pool)-1 {
+		window = len(pool) - 1
+	}
+	excluded := make(map[uint64]bool, window)
+	for _, cid := range p.recent[len(p.recent)-window:] {
+		excluded[cid] = true
+	}
+
+	candidates := make([]uint64, 0, len(pool))
+	weights := make([]float64, 0, len(pool))
+	total := 0.0
+	for _, cid := range pool {
+		if excluded[cid] {
+			continue
+		}
+		w := Weight(stats(cid), now)
+		candidates = append(candidates, cid)
+		weights = append(weights, w)
+		total += w
+	}
+	if len(candidates) == 0 {
+		return 0, false
+	}
+
+	r := p.rng.Float64() * total
+	for i, w := range weights {
+		r -= w
+		if r < 0 {
+			return candidates[i], true
+		}
+	}
+	return candidates[len(candidates)-1], true
+}
+
+// Shuffle 用 Picker 的随机源做均匀洗牌
+func (p *Picker) Shuffle(list []uint64) {
+	p.rng.Shuffle(len(list), func(i, j int) { list[i], list[j] = list[j], list[i] })
+}
@@ -0,0 +1,139 @@
+package shuffle
+
+import (
+	"testing"
+	"time"
+)
+
+var now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func noStats(uint64) Stats { return Stats{} }
+
+func TestWeight(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Stats // 期望 a 的权重大于 b
+	}{
+		{"评分高", Stats{Rating: 5}, Stats{Rating: 2}},
+		{"未评分按 3 星", Stats{}, Stats{Rating: 2}},
+		{"播放次数少", Stats{PlayCount: 1}, Stats{PlayCount: 20}},
+		{"很久没播放", Stats{LastPlayed: now.Add(-7 * 24 * time.Hour)}, Stats{LastPlayed: now.Add(-time.Hour)}},
+		{"从未播放", Stats{}, Stats{LastPlayed: now}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if wa, wb := Weight(tt.a, now), Weight(tt.b, now); wa <= wb {
+				t.Errorf("Weight(a) = %v, 应大于 Weight(b) = %v", wa, wb)
+			}
+		})
+	}
+	if w := Weight(Stats{LastPlayed: now.Add(time.Hour)}, now); w <= 0 {
+		t.Errorf("上次播放时间在未来时权重应为正数，得到 %v", w)
+	}
+}
+
+func TestRecent(t *testing.T) {
+	tests := []struct {
+		name   string
+		window int
+		played []uint64
+		cid    uint64
+		want   bool
+	}{
+		{"窗口内", 3, []uint64{1, 2, 3}, 1, true},
+		{"移出窗口", 3, []uint64{1, 2, 3, 4}, 1, false},
+		{"最新的一首", 3, []uint64{1, 2, 3, 4}, 4, true},
+		{"未播放", 3, []uint64{1, 2}, 9, false},
+		{"窗口为 0", 0, []uint64{1}, 1, false},
+		{"负数窗口按 0", -1, []uint64{1}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPicker(1, tt.window)
+			for _, cid := range tt.played {
+				p.Played(cid)
+			}
+			if got := p.Recent(tt.cid); got != tt.want {
+				t.Errorf("Recent(%d) = %v, want %v", tt.cid, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextNoRepeatWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		window int
+		pool   []uint64
+		played []uint64
+		never  []uint64 // 不应抽中的曲目
+	}{
+		{"排除窗口内的曲目", 2, []uint64{1, 2, 3, 4}, []uint64{1, 2}, []uint64{1, 2}},
+		{"池比窗口小时只排除最近 len(pool)-1 首", 5, []uint64{1, 2, 3}, []uint64{1, 2, 3}, []uint64{2, 3}},
+		{"只有一首时照常抽取", 5, []uint64{7}, []uint64{7}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPicker(42, tt.window)
+			for _, cid := range tt.played {
+				p.Played(cid)
+			}
+			for i := 0; i < 200; i++ {
+				cid, ok := p.Next(tt.pool, noStats, now)
+				if !ok {
+					t.Fatal("Next 没有抽到曲目")
+				}
+				for _, n := range tt.never {
+					if cid == n {
+						t.Fatalf("抽到了窗口内的 %d", cid)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNextEmptyPool(t *testing.T) {
+	if _, ok := NewPicker(1, 3).Next(nil, noStats, now); ok {
+		t.Error("空的播放范围不应抽到曲目")
+	}
+}
+
+func TestNextDeterministic(t *testing.T) {
+	pool := []uint64{1, 2, 3, 4, 5, 6, 7, 8}
+	draw := func() []uint64 {
+		p := NewPicker(2024, 3)
+		var got []uint64
+		for i := 0; i < 20; i++ {
+			cid, _ := p.Next(pool, noStats, now)
+			p.Played(cid)
+			got = append(got, cid)
+		}
+		return got
+	}
+	a, b := draw(), draw()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("相同种子的抽取结果不同: %v / %v", a, b)
+		}
+	}
+}
+
+func TestNextFollowsWeights(t *testing.T) {
+	stats := func(cid uint64) Stats {
+		if cid == 1 {
+			return Stats{Rating: 5}
+		}
+		return Stats{Rating: 1}
+	}
+	p := NewPicker(7, 0)
+	counts := make(map[uint64]int)
+	for i := 0; i < 2000; i++ {
+		cid, _ := p.Next([]uint64{1, 2}, stats, now)
+		counts[cid]++
+	}
+	// 权重比为 25:1
+	if counts[1] < 10*counts[2] {
+		t.Errorf("5 星的曲目应远多于 1 星的: %v", counts)
+	}
+}
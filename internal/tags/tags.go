@@ -0,0 +1,170 @@
+package tags
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// 标签不区分大小写，统一保存为小写
+
+// Normalize 去掉首尾空白并转成小写，去掉开头的 #
+func Normalize(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// Split 把用空格或逗号分隔的输入拆成标签，去掉重复
+func Split(s string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '，' || unicode.IsSpace(r) }) {
+		if t := Normalize(f); t != "" && !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// ========== 标签表达式 ==========
+// 标签名之间用 & 表示且（也可只用空格）、| 表示或、! 表示非，括号改变优先级，
+// 如 piano & !live、(vocaloid | utau) bgm
+
+// Expr 是解析后的标签表达式
+type Expr interface {
+	Match(tags map[string]bool) bool
+}
+
+type tagExpr string
+
+func (e tagExpr) Match(tags map[string]bool) bool { return tags[string(e)] }
+
+type notExpr struct{ e Expr }
+
+func (e notExpr) Match(tags map[string]bool) bool { return !e.e.Match(tags) }
+
+type andExpr struct{ a, b Expr }
+
+func (e andExpr) Match(tags map[string]bool) bool { return e.a.Match(tags) && e.b.Match(tags) }
+
+type orExpr struct{ a, b Expr }
+
+func (e orExpr) Match(tags map[string]bool) bool { return e.a.Match(tags) || e.b.Match(tags) }
+
+// Parse 解析标签表达式
+func Parse(s string) (Expr, error) {
+	p := &parser{tokens: tokenize(s)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("标签表达式为空")
+	}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("标签表达式中多余的 %q", p.tokens[p.pos])
+	}
+	return e, nil
+}
+
+const operators = "&|!()"
+
+func tokenize(s string) []string {
+	var tokens []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case strings.ContainsRune(operators, r):
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) or() (Expr, error) {
+	e, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "|" {
+		p.pos++
+		rhs, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		e = orExpr{e, rhs}
+	}
+	return e, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	e, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch tok := p.peek(); tok {
+		case "", "|", ")":
+			return e, nil
+		case "&":
+			p.pos++
+		}
+		rhs, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		e = andExpr{e, rhs}
+	}
+}
+
+func (p *parser) unary() (Expr, error) {
+	tok := p.peek()
+	p.pos++
+	switch tok {
+	case "":
+		return nil, fmt.Errorf("标签表达式不完整")
+	case "!":
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	case "(":
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("标签表达式缺少 )")
+		}
+		p.pos++
+		return e, nil
+	case "&", "|", ")":
+		return nil, fmt.Errorf("标签表达式中多余的 %q", tok)
+	}
+	return tagExpr(Normalize(tok)), nil
+}
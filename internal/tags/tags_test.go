@@ -0,0 +1,83 @@
+package tags
+
+import (
+	"slices"
+	"testing"
+)
+
+func set(tags ...string) map[string]bool {
+	s := make(map[string]bool)
+	for _, t := range tags {
+		s[t] = true
+	}
+	return s
+}
+
+func TestParseMatch(t *testing.T) {
+	tests := []struct {
+		expr  string
+		tags  map[string]bool
+		match bool
+	}{
+		{"piano", set("piano"), true},
+		{"Piano", set("piano"), true},
+		{"#piano", set("piano"), true},
+		{"piano", set("live"), false},
+		// 隐式 &
+		{"piano live", set("piano", "live"), true},
+		{"piano live", set("piano"), false},
+		{"piano & !live", set("piano"), true},
+		{"piano & !live", set("piano", "live"), false},
+		// & 优先于 |
+		{"a | b & c", set("a"), true},
+		{"a | b & c", set("b"), false},
+		{"a | b c", set("b", "c"), true},
+		{"(a | b) & c", set("a"), false},
+		{"(a | b) & c", set("a", "c"), true},
+		// ! 只作用于紧跟的一项
+		{"!a & b", set("b"), true},
+		{"!a | b", set("a"), false},
+		{"!(a | b)", set("c"), true},
+		{"!(a | b)", set("b"), false},
+		{"!!a", set("a"), true},
+		{"(vocaloid|utau)bgm", set("utau", "bgm"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			if got := e.Match(tt.tags); got != tt.match {
+				t.Errorf("Parse(%q).Match(%v) = %v，期望 %v", tt.expr, tt.tags, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"   ",
+		"(a | b",
+		"a | b)",
+		"((a)",
+		"()",
+		"a &",
+		"| a",
+		"a | | b",
+		"!",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) 应返回错误", expr)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	got := Split(" Piano, #live，piano  bgm ")
+	want := []string{"piano", "live", "bgm"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Split = %v，期望 %v", got, want)
+	}
+}
@@ -42,3 +42,15 @@ var DefaultKeyMap = KeyMap{
 		key.WithHelp("q", "退出"),
 	),
 }
+
+// ShortHelp / FullHelp 实现 help.KeyMap
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down, k.Expand, k.Collapse, k.ToggleSelect, k.Play, k.Quit}
+}
+
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.Expand, k.Collapse},
+		{k.ToggleSelect, k.Play, k.Quit},
+	}
+}
@@ -99,7 +99,6 @@ func (u *UI) View() string {
 
 	nodes := u.model.GetVisibleNodes()
 	cursor := u.model.GetCursor()
-	selected := u.model.GetSelectedCIDs()
 
 	var lines []string
 	lines = append(lines, titleStyle.Render("BiliCLI - 树形视频浏览器"))
@@ -121,7 +120,7 @@ func (u *UI) View() string {
 	// 显示节点
 	for i := start; i < len(nodes) && i < start+maxLines; i++ {
 		node := nodes[i]
-		line := u.renderNode(node, i == cursor, selected)
+		line := u.renderNode(node, i == cursor)
 		lines = append(lines, line)
 	}
 
@@ -133,7 +132,7 @@ func (u *UI) View() string {
 	return strings.Join(lines, "\n")
 }
 
-func (u *UI) renderNode(node tree.Node, isCursor bool, selected map[uint64]bool) string {
+func (u *UI) renderNode(node tree.Node, isCursor bool) string {
 	line := node.Display()
 
 	if isCursor {
@@ -143,7 +142,7 @@ func (u *UI) renderNode(node tree.Node, isCursor bool, selected map[uint64]bool)
 	}
 
 	// 如果是叶子节点且被选中，高亮显示
-	if node.IsLeaf() && selected[node.CID] {
+	if node.IsLeaf() && u.model.IsSelected(node.CID) {
 		line = selectedStyle.Render(line)
 	}
 
@@ -0,0 +1,34 @@
+package userdata
+
+// A-B 循环书签，单位秒
+const bookmarksFile = "bookmarks.json"
+
+type Bookmark struct {
+	Name string  `json:"name"`
+	A    float64 `json:"a"`
+	B    float64 `json:"b"`
+}
+
+func LoadBookmarks() (map[uint64][]Bookmark, error) {
+	bookmarks := make(map[uint64][]Bookmark)
+	if err := load(bookmarksFile, &bookmarks); err != nil {
+		return bookmarks, err
+	}
+	return bookmarks, nil
+}
+
+// SaveBookmark 保存书签，同名书签会被覆盖
+func SaveBookmark(cid uint64, bm Bookmark) error {
+	bookmarks, err := LoadBookmarks()
+	if err != nil {
+		return err
+	}
+	var kept []Bookmark
+	for _, b := range bookmarks[cid] {
+		if b.Name != bm.Name {
+			kept = append(kept, b)
+		}
+	}
+	bookmarks[cid] = append(kept, bm)
+	return save(bookmarksFile, bookmarks)
+}
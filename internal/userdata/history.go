@@ -0,0 +1,72 @@
+package userdata
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// 收听记录：每次开始、听完、跳过各追加一行 JSON，只增不改
+const historyFile = "history.jsonl"
+
+// HistoryEvent.Event 的取值
+const (
+	HistoryStart  = "start"
+	HistoryFinish = "finish"
+	HistorySkip   = "skip" // 没听完就切走（下一首、停止、退出等）
+)
+
+type HistoryEvent struct {
+	Time      int64   `json:"time"` // Unix 时间戳
+	CID       uint64  `json:"cid"`
+	Event     string  `json:"event"`
+	Pos       float64 `json:"pos"` // 开始时为起播位置，其余为结束时播放到的位置，单位秒
+	Completed bool    `json:"completed"`
+}
+
+// AppendHistory 向收听记录追加一条
+func AppendHistory(e HistoryEvent) error {
+	if err := os.MkdirAll(Dir(), 0o755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %w", err)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("序列化 %s 失败: %w", historyFile, err)
+	}
+	f, err := os.OpenFile(Path(historyFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", historyFile, err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", historyFile, err)
+	}
+	return nil
+}
+
+// LoadHistory 按时间顺序读取全部收听记录，跳过损坏的行（如写到一半时断电）
+func LoadHistory() ([]HistoryEvent, error) {
+	f, err := os.Open(Path(historyFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", historyFile, err)
+	}
+	defer f.Close()
+	var events []HistoryEvent
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e HistoryEvent
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			events = append(events, e)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return events, fmt.Errorf("读取 %s 失败: %w", historyFile, err)
+	}
+	return events, nil
+}
@@ -0,0 +1,23 @@
+package userdata
+
+// 响度由 mytui analyze 测量写入
+const loudnessFile = "loudness.json"
+
+type Loudness struct {
+	Integrated float64 `json:"integrated"` // 综合响度，LUFS
+	Peak       float64 `json:"peak"`       // 真峰值，dBTP
+	Analyzed   int64   `json:"analyzed"`   // Unix 时间戳
+}
+
+func LoadLoudness() (map[uint64]Loudness, error) {
+	loudness := make(map[uint64]Loudness)
+	if err := load(loudnessFile, &loudness); err != nil {
+		return loudness, err
+	}
+	return loudness, nil
+}
+
+// SaveLoudness 整体写回测量结果
+func SaveLoudness(loudness map[uint64]Loudness) error {
+	return save(loudnessFile, loudness)
+}
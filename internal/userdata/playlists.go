@@ -0,0 +1,28 @@
+package userdata
+
+// 命名播放列表，按创建顺序保存，条目是 CID（或分段 ID）的引用；
+// 智能播放列表只保存查询，条目在每次打开或播放时重新计算
+const playlistsFile = "playlists.json"
+
+type Playlist struct {
+	Name  string   `json:"name"`
+	Items []uint64 `json:"items"`
+	Query string   `json:"query,omitempty"` // 非空时为智能播放列表
+}
+
+func (p Playlist) Smart() bool {
+	return p.Query != ""
+}
+
+func LoadPlaylists() ([]Playlist, error) {
+	var playlists []Playlist
+	err := load(playlistsFile, &playlists)
+	return playlists, err
+}
+
+func SavePlaylists(playlists []Playlist) error {
+	if playlists == nil {
+		playlists = []Playlist{}
+	}
+	return save(playlistsFile, playlists)
+}
@@ -0,0 +1,34 @@
+package userdata
+
+// 播放进度由 TUI 在停止 / 跳过 / 退出时写入，单位秒
+const positionsFile = "positions.json"
+
+type Position struct {
+	Pos      float64 `json:"pos"`
+	Duration float64 `json:"duration"`
+	Finished bool    `json:"finished"`
+	Updated  int64   `json:"updated"` // Unix 时间戳
+}
+
+// InProgress 表示听了一部分、还没听完
+func (p Position) InProgress() bool {
+	return !p.Finished && p.Pos > 0
+}
+
+func LoadPositions() (map[uint64]Position, error) {
+	positions := make(map[uint64]Position)
+	if err := load(positionsFile, &positions); err != nil {
+		return positions, err
+	}
+	return positions, nil
+}
+
+// SavePosition 更新单个 CID 的播放进度
+func SavePosition(cid uint64, p Position) error {
+	positions, err := LoadPositions()
+	if err != nil {
+		return err
+	}
+	positions[cid] = p
+	return save(positionsFile, positions)
+}
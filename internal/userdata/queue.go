@@ -0,0 +1,21 @@
+package userdata
+
+// 播放队列，退出后保留，下次启动时恢复（不自动开始播放）
+const queueFile = "queue.json"
+
+type Queue struct {
+	Items []uint64 `json:"items"`
+	Index int      `json:"index"` // 当前曲目在 Items 中的位置
+	Pool  []uint64 `json:"pool"`  // 播放范围，随机类模式和电台从中抽取
+	Radio []uint64 `json:"radio"` // 由电台模式追加的曲目
+}
+
+func LoadQueue() (Queue, error) {
+	var q Queue
+	err := load(queueFile, &q)
+	return q, err
+}
+
+func SaveQueue(q Queue) error {
+	return save(queueFile, q)
+}
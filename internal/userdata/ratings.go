@@ -0,0 +1,42 @@
+package userdata
+
+// 收藏和评分，条目按 CID、标题按 TitleKey 保存，重新构建 tree.json 不会丢失
+const ratingsFile = "ratings.json"
+
+// TitleKey 返回标题在评分和标签中的键，不同分组中的同名标题互不影响
+func TitleKey(group, title string) string {
+	return group + "\x00" + title
+}
+
+type Rating struct {
+	Favourite bool `json:"favourite,omitempty"`
+	Stars     int  `json:"stars,omitempty"` // 0 表示未评分，否则 1-5
+}
+
+func (r Rating) Empty() bool {
+	return !r.Favourite && r.Stars == 0
+}
+
+type Ratings struct {
+	Items  map[uint64]Rating `json:"items"`
+	Titles map[string]Rating `json:"titles"` // 键为 TitleKey
+}
+
+func LoadRatings() (Ratings, error) {
+	r := Ratings{Items: make(map[uint64]Rating), Titles: make(map[string]Rating)}
+	if err := load(ratingsFile, &r); err != nil {
+		return r, err
+	}
+	// 文件中某一部分为 null 时 json 会把 map 置为 nil
+	if r.Items == nil {
+		r.Items = make(map[uint64]Rating)
+	}
+	if r.Titles == nil {
+		r.Titles = make(map[string]Rating)
+	}
+	return r, nil
+}
+
+func SaveRatings(r Ratings) error {
+	return save(ratingsFile, r)
+}
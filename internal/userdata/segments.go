@@ -0,0 +1,31 @@
+package userdata
+
+// 长视频（串烧、演唱会）按 CUE 或时间戳列表切成的分段，单位秒
+const segmentsFile = "segments.json"
+
+type Segment struct {
+	Title string  `json:"title"`
+	Start float64 `json:"start"` // 在整个视频中的起点，下一段的起点即本段终点
+}
+
+func LoadSegments() (map[uint64][]Segment, error) {
+	segments := make(map[uint64][]Segment)
+	if err := load(segmentsFile, &segments); err != nil {
+		return segments, err
+	}
+	return segments, nil
+}
+
+// SaveSegments 替换单个 CID 的分段，segs 为空时删除
+func SaveSegments(cid uint64, segs []Segment) error {
+	segments, err := LoadSegments()
+	if err != nil {
+		return err
+	}
+	if len(segs) == 0 {
+		delete(segments, cid)
+	} else {
+		segments[cid] = segs
+	}
+	return save(segmentsFile, segments)
+}
@@ -0,0 +1,43 @@
+package userdata
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// "随机不重复"模式的袋子：每个播放范围（一组 CID）各自记录尚未播放的部分，跨会话保留
+const shuffleBagFile = "shufflebag.json"
+
+// PoolKey 由播放范围内的 CID 集合计算，与顺序无关
+func PoolKey(cids []uint64) string {
+	sorted := append([]uint64(nil), cids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	h := fnv.New64a()
+	for _, cid := range sorted {
+		fmt.Fprintf(h, "%d,", cid)
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// LoadShuffleBag 返回该范围袋子里剩下的 CID（按上次洗好的顺序）
+func LoadShuffleBag(key string) ([]uint64, error) {
+	bags := make(map[string][]uint64)
+	if err := load(shuffleBagFile, &bags); err != nil {
+		return nil, err
+	}
+	return bags[key], nil
+}
+
+func SaveShuffleBag(key string, remaining []uint64) error {
+	bags := make(map[string][]uint64)
+	if err := load(shuffleBagFile, &bags); err != nil {
+		return err
+	}
+	if len(remaining) == 0 {
+		delete(bags, key)
+	} else {
+		bags[key] = remaining
+	}
+	return save(shuffleBagFile, bags)
+}
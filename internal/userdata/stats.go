@@ -0,0 +1,29 @@
+package userdata
+
+// 每首曲目的收听统计，随收听记录一起更新
+const statsFile = "stats.json"
+
+type Stats struct {
+	Plays      int     `json:"plays"`       // 听完的次数
+	Skips      int     `json:"skips"`       // 没听完就切走的次数
+	Listened   float64 `json:"listened"`    // 累计收听时长，秒
+	LastPlayed int64   `json:"last_played"` // 最近一次开始播放的 Unix 时间戳，0 为从未播放
+}
+
+func LoadStats() (map[uint64]Stats, error) {
+	stats := make(map[uint64]Stats)
+	if err := load(statsFile, &stats); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// SaveStats 更新单个 CID 的统计
+func SaveStats(cid uint64, s Stats) error {
+	stats, err := LoadStats()
+	if err != nil {
+		return err
+	}
+	stats[cid] = s
+	return save(statsFile, stats)
+}
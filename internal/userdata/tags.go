@@ -0,0 +1,36 @@
+package userdata
+
+// 用户标签，条目按 CID、标题按 TitleKey、分组按名称保存；子节点继承父节点的标签
+const tagsFile = "tags.json"
+
+type Tags struct {
+	Items  map[uint64][]string `json:"items"`
+	Titles map[string][]string `json:"titles"` // 键为 TitleKey
+	Groups map[string][]string `json:"groups"`
+}
+
+func LoadTags() (Tags, error) {
+	t := Tags{
+		Items:  make(map[uint64][]string),
+		Titles: make(map[string][]string),
+		Groups: make(map[string][]string),
+	}
+	if err := load(tagsFile, &t); err != nil {
+		return t, err
+	}
+	// 文件中某一部分为 null 时 json 会把 map 置为 nil
+	if t.Items == nil {
+		t.Items = make(map[uint64][]string)
+	}
+	if t.Titles == nil {
+		t.Titles = make(map[string][]string)
+	}
+	if t.Groups == nil {
+		t.Groups = make(map[string][]string)
+	}
+	return t, nil
+}
+
+func SaveTags(t Tags) error {
+	return save(tagsFile, t)
+}
@@ -0,0 +1,38 @@
+package userdata
+
+// 开头 / 结尾静音的裁剪位置，自动检测的结果和手动设置的都存在这里
+const trimsFile = "trims.json"
+
+type Trim struct {
+	Start  float64 `json:"start"`  // 从这里开始播放，秒
+	End    float64 `json:"end"`    // 播放到这里结束，0 为不裁剪结尾
+	Manual bool    `json:"manual"` // 手动设置，不会被自动检测覆盖
+}
+
+func LoadTrims() (map[uint64]Trim, error) {
+	trims := make(map[uint64]Trim)
+	if err := load(trimsFile, &trims); err != nil {
+		return trims, err
+	}
+	return trims, nil
+}
+
+// SaveTrim 更新单个 CID 的裁剪位置
+func SaveTrim(cid uint64, t Trim) error {
+	trims, err := LoadTrims()
+	if err != nil {
+		return err
+	}
+	trims[cid] = t
+	return save(trimsFile, trims)
+}
+
+// DeleteTrim 删除单个 CID 的裁剪位置，下次播放时重新检测
+func DeleteTrim(cid uint64) error {
+	trims, err := LoadTrims()
+	if err != nil {
+		return err
+	}
+	delete(trims, cid)
+	return save(trimsFile, trims)
+}
@@ -0,0 +1,62 @@
+package userdata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// 用户数据（书签、播放进度等）与 tree.json 分开存放，重新构建索引不会丢失
+const appName = "bilimusicplayer"
+
+// Dir 返回用户数据目录：$XDG_DATA_HOME/bilimusicplayer，默认 ~/.local/share/bilimusicplayer
+func Dir() string {
+	base := os.Getenv("XDG_DATA_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		base = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(base, appName)
+}
+
+// Path 返回数据目录下的文件路径
+func Path(name string) string {
+	return filepath.Join(Dir(), name)
+}
+
+// load 读取 JSON 文件到 v，文件不存在时保持 v 不变
+func load(name string, v any) error {
+	data, err := os.ReadFile(Path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("解析 %s 失败: %w", name, err)
+	}
+	return nil
+}
+
+// save 先写临时文件再改名，避免异常退出时留下写了一半的文件
+func save(name string, v any) error {
+	if err := os.MkdirAll(Dir(), 0o755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %w", err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 %s 失败: %w", name, err)
+	}
+	tmp := Path(name) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", name, err)
+	}
+	return os.Rename(tmp, Path(name))
+}
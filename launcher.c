@@ -16,9 +16,7 @@ int main(void)
     }
 
     wait(NULL);                   /* 等 mytui 结束 */
-    system("pkill -x fake_hex");   // -x 表示精确匹配进程名（推荐！）
-    usleep(200000);               /* 0.2 s 兜底延迟 */
-//    system("pkill -f play");
-    system("pkill -x ffplay");     // ffplay 是实际播放进程，比 -f play 更安全
+    /* mytui 崩溃时按它记录的进程组清理 ffplay / fake_hex，不再 pkill 误杀别人的进程 */
+    system("cmd/tui/mytui --cleanup");
     return 0;
 }